@@ -0,0 +1,64 @@
+package cloudflare
+
+import (
+	"time"
+)
+
+/* Interfaces of the client API, split by concern */
+
+type ZonesAPI interface {
+	GetDomainsList() (RootZones, error)
+	GetActiveZones(domain string, zones ...string) (RootZonesCheck, error)
+	Snapshot(domain, zoneid string) (Root, error)
+}
+
+type RecordsAPI interface {
+	GetDnsRecords(domain string) (RootDnsRecords, error)
+	NewDnsRecord(domain string, values map[string]string) (RootNewRecord, error)
+	EditDnsRecord(domain, id string, values map[string]string) (RootEditRecord, error)
+	DeleteDnsRecord(domain, id string) (Root, error)
+	SetProxyStatus(domain, id string, status bool) (RootEditRecord, error)
+}
+
+type SettingsAPI interface {
+	GetZoneSettings(domain string) (RootZoneSettings, error)
+	SetSecurityLevel(domain, level string) (RootZones, error)
+	SetCacheLevel(domain, level string) (RootZones, error)
+	UpdateSecurityLevel(domain, level string) (RootSecLevel, error)
+	UpdateCacheLevel(domain, level string) (RootCacheLevel, error)
+	SetDevMode(domain string, enable bool) (time.Time, error)
+	ToggleDevMode(domain string, enable bool) (RootDevMode, error)
+	ToggleIpv46(domain string, toggle bool) (Root, error)
+	ToggleMirage2(domain string, toggle bool) (Root, error)
+	Minify(domain, state string) (Root, error)
+	SetRocketLoader(domain, state string) (Root, error)
+}
+
+type CacheAPI interface {
+	PurgeCache(domain string) (RootPurgeCache, error)
+	PurgeFile(domain, url_file string) (RootPurgeFile, error)
+}
+
+type FirewallAPI interface {
+	LookupIp(domain, ip string) (RootLookupIp, error)
+	DenyIP(domain, ip string) (RootModIp, error)
+	AllowIP(domain, ip string) (RootModIp, error)
+	ForgetIP(domain, ip string) (RootModIp, error)
+}
+
+type StatsAPI interface {
+	GetDomainStats(domain, interval string) (RootStats, error)
+	GetRecentIps(domain, hours, class, geo string) (RootZoneIps, error)
+}
+
+// API is the whole client API, implemented by Cloudflare and by Memory.
+type API interface {
+	ZonesAPI
+	RecordsAPI
+	SettingsAPI
+	CacheAPI
+	FirewallAPI
+	StatsAPI
+}
+
+var _ API = (*Cloudflare)(nil)
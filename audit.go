@@ -0,0 +1,85 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Request parameters never written to the audit log as they are.
+var redactedParams = map[string]bool{
+	"tkn": true,
+}
+
+type AuditEntry struct {
+	Time     time.Time         `json:"time"`
+	Operator string            `json:"operator"`
+	Action   string            `json:"action"`
+	Zone     string            `json:"zone"`
+	Params   map[string]string `json:"params"`
+	Result   string            `json:"result"`
+	Message  string            `json:"msg,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// audit appends one JSON line describing a mutating request and its outcome
+// to the Audit writer. Failing to write is logged but does not fail the
+// request, which has already been sent.
+func (this *Cloudflare) audit(values url.Values, content []byte, err error) {
+	entry := AuditEntry{
+		Time:     time.Now().UTC(),
+		Operator: this.Operator,
+		Action:   values.Get("a"),
+		Zone:     values.Get("z"),
+		Params:   make(map[string]string),
+	}
+	if entry.Operator == "" {
+		entry.Operator = this.Email
+	}
+
+	for k := range values {
+		switch {
+		case k == "a" || k == "z" || k == "email":
+		case redactedParams[k]:
+			entry.Params[k] = "REDACTED"
+		default:
+			entry.Params[k] = values.Get(k)
+		}
+	}
+
+	if err != nil {
+		entry.Result = "error"
+		entry.Error = err.Error()
+	} else {
+		outcome := struct {
+			Result  string `json:"result"`
+			Message string `json:"msg"`
+		}{}
+		if err := json.Unmarshal(content, &outcome); err != nil {
+			entry.Result = "error"
+			entry.Error = err.Error()
+		} else {
+			entry.Result = outcome.Result
+			entry.Message = outcome.Message
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	this.auditMu.Lock()
+	defer this.auditMu.Unlock()
+	if _, err := this.Audit.Write(append(line, '\n')); err != nil {
+		log.Println(err)
+	}
+}
+
+// OpenAuditLog opens path for appending, creating it when missing.
+func OpenAuditLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+}
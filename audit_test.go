@@ -0,0 +1,71 @@
+package cloudflare
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	cf, server := newTestClient(t, func(values url.Values) string {
+		if values.Get("z") == "example.org" {
+			return `{"result":"error","msg":"Invalid zone"}`
+		}
+		return `{"result":"success","msg":null,"response":{}}`
+	})
+	var audit bytes.Buffer
+	cf.Audit = &audit
+
+	cf.GetDnsRecords("example.com")
+	cf.SetSecurityLevel("example.com", SEC_LVL_HIGH)
+	cf.Operator = "alice"
+	if _, err := cf.DeleteDnsRecord("example.org", "42"); err == nil {
+		t.Error("error result not returned")
+	}
+	if actions := server.actions(); !reflect.DeepEqual(actions, []string{"rec_load_all", "sec_lvl", "rec_delete"}) {
+		t.Errorf("actions %v", actions)
+	}
+	server.Close()
+	if _, err := cf.DenyIP("example.com", "192.0.2.4"); err == nil {
+		t.Error("request to a closed server succeeded")
+	}
+
+	if strings.Contains(audit.String(), "secret-key") {
+		t.Errorf("api key written to the audit log:\n%s", audit.String())
+	}
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+		entry := AuditEntry{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("%s: %s", line, err)
+		}
+		if entry.Time.IsZero() {
+			t.Errorf("%s: no time", line)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3 without the rec_load_all read:\n%s", len(entries), audit.String())
+	}
+
+	want := []AuditEntry{
+		{Operator: "user@example.com", Action: "sec_lvl", Zone: "example.com", Params: map[string]string{"v": SEC_LVL_HIGH, "tkn": "REDACTED"}, Result: "success"},
+		{Operator: "alice", Action: "rec_delete", Zone: "example.org", Params: map[string]string{"id": "42", "tkn": "REDACTED"}, Result: "error", Message: "Invalid zone"},
+		{Operator: "alice", Action: "ban", Zone: "example.com", Params: map[string]string{"key": "192.0.2.4", "tkn": "REDACTED"}, Result: "error"},
+	}
+	for i, entry := range entries {
+		if i == 2 {
+			if entry.Error == "" {
+				t.Errorf("entry %d: no error recorded", i)
+			}
+			entry.Error = ""
+		}
+		entry.Time = want[i].Time
+		if !reflect.DeepEqual(entry, want[i]) {
+			t.Errorf("entry %d:\n got %+v\nwant %+v", i, entry, want[i])
+		}
+	}
+}
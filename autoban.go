@@ -0,0 +1,214 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	BAN_BAN   string = "ban"
+	BAN_SKIP  string = "skip"
+	BAN_ERROR string = "error"
+)
+
+/* Automatic banning of threat IPs reported by zone_ips */
+
+type BanRules struct {
+	MinHits int
+	Allow   []string
+	Regions []GeoBox
+}
+
+// GeoBox limits banning to addresses located inside it.
+type GeoBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+func (this GeoBox) Contains(latitude, longitude float64) bool {
+	return latitude >= this.MinLatitude && latitude <= this.MaxLatitude &&
+		longitude >= this.MinLongitude && longitude <= this.MaxLongitude
+}
+
+type BanDecision struct {
+	Time      time.Time `json:"time"`
+	Zone      string    `json:"zone"`
+	Ip        string    `json:"ip"`
+	Hits      int       `json:"hits"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	DryRun    bool      `json:"dry_run"`
+}
+
+type AutoBan struct {
+	Client   API
+	Domain   string
+	Hours    int
+	Rules    BanRules
+	Interval time.Duration
+	DryRun   bool
+	Log      io.Writer
+
+	mu     sync.Mutex
+	banned map[string]bool
+}
+
+// Check fetches the threat IPs seen in the last Hours, applies the rules and
+// bans the offenders. Every IP gets a decision, which is also written to Log
+// as a JSON line.
+func (this *AutoBan) Check() ([]BanDecision, error) {
+	allow, err := parseAllowList(this.Rules.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := this.Hours
+	if hours <= 0 {
+		hours = 24
+	}
+	result, err := this.Client.GetRecentIps(this.Domain, strconv.Itoa(hours), "t", "1")
+	if err != nil {
+		return nil, err
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.banned == nil {
+		this.banned = make(map[string]bool)
+	}
+
+	var decisions []BanDecision
+	for _, ip := range result.Response.Ips {
+		decision := this.decide(ip, allow)
+		if decision.Action == BAN_BAN && !decision.DryRun {
+			if _, err := this.Client.DenyIP(this.Domain, decision.Ip); err != nil {
+				decision.Action = BAN_ERROR
+				decision.Reason = err.Error()
+			} else {
+				this.banned[decision.Ip] = true
+			}
+		}
+		decisions = append(decisions, decision)
+		if err := this.record(decision); err != nil {
+			return decisions, err
+		}
+	}
+	return decisions, nil
+}
+
+func (this *AutoBan) decide(ip Ip, allow []*net.IPNet) BanDecision {
+	hits, _ := strconv.Atoi(ip.Hits)
+	decision := BanDecision{
+		Time:      time.Now().UTC(),
+		Zone:      this.Domain,
+		Ip:        ip.Ip,
+		Hits:      hits,
+		Latitude:  ip.Latitude,
+		Longitude: ip.Longitude,
+		Action:    BAN_SKIP,
+		DryRun:    this.DryRun,
+	}
+
+	address := net.ParseIP(ip.Ip)
+	switch {
+	case address == nil:
+		decision.Reason = "invalid address"
+	case this.banned[ip.Ip]:
+		decision.Reason = "already banned"
+	case hits < this.Rules.MinHits:
+		decision.Reason = fmt.Sprintf("%d hits below threshold %d", hits, this.Rules.MinHits)
+	case inNetworks(allow, address):
+		decision.Reason = "allowlisted"
+	case !inRegions(this.Rules.Regions, ip.Latitude, ip.Longitude):
+		decision.Reason = "outside configured regions"
+	default:
+		decision.Action = BAN_BAN
+		decision.Reason = fmt.Sprintf("%d threat hits", hits)
+	}
+	return decision
+}
+
+func (this *AutoBan) record(decision BanDecision) error {
+	if this.Log == nil {
+		return nil
+	}
+	line, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+	_, err = this.Log.Write(append(line, '\n'))
+	return err
+}
+
+// Run calls Check every Interval until stop is closed.
+func (this *AutoBan) Run(stop <-chan struct{}) {
+	interval := this.Interval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := this.Check(); err != nil {
+			log.Println(this.Domain, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func parseAllowList(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		normalized, err := NormalizeIp(entry)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(normalized); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, _ := net.ParseCIDR(normalized)
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func inNetworks(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRegions(regions []GeoBox, latitude, longitude float64) bool {
+	if len(regions) == 0 {
+		return true
+	}
+	for _, region := range regions {
+		if region.Contains(latitude, longitude) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package cloudflare
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAutoBanCheck(t *testing.T) {
+	memory := newTestMemory()
+	for _, ip := range []Ip{
+		{Ip: "198.51.100.1", Classification: "threat", Hits: "50", Latitude: 48.8, Longitude: 2.3},
+		{Ip: "198.51.100.2", Classification: "threat", Hits: "3", Latitude: 48.8, Longitude: 2.3},
+		{Ip: "203.0.113.9", Classification: "threat", Hits: "80", Latitude: 48.8, Longitude: 2.3},
+		{Ip: "198.51.100.3", Classification: "threat", Hits: "90", Latitude: 40.7, Longitude: -74.0},
+		{Ip: "198.51.100.4", Classification: "robot", Hits: "500", Latitude: 48.8, Longitude: 2.3},
+		{Ip: "not-an-ip", Classification: "threat", Hits: "99"},
+	} {
+		if err := memory.AddRecentIp("example.com", ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var log bytes.Buffer
+	autoban := &AutoBan{
+		Client: memory,
+		Domain: "example.com",
+		Rules: BanRules{
+			MinHits: 10,
+			Allow:   []string{"203.0.113.0/24"},
+			Regions: []GeoBox{{MinLatitude: 35, MaxLatitude: 60, MinLongitude: -10, MaxLongitude: 30}},
+		},
+		Log: &log,
+	}
+	decisions, err := autoban.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ ip, action, reason string }{
+		{"198.51.100.1", BAN_BAN, "50 threat hits"},
+		{"198.51.100.2", BAN_SKIP, "3 hits below threshold 10"},
+		{"203.0.113.9", BAN_SKIP, "allowlisted"},
+		{"198.51.100.3", BAN_SKIP, "outside configured regions"},
+		{"not-an-ip", BAN_SKIP, "invalid address"},
+	}
+	if len(decisions) != len(want) {
+		t.Fatalf("decisions %+v, want %d", decisions, len(want))
+	}
+	for i, decision := range decisions {
+		if decision.Ip != want[i].ip || decision.Action != want[i].action || decision.Reason != want[i].reason {
+			t.Errorf("decision %d: %s %s %q, want %s %s %q", i, decision.Ip, decision.Action, decision.Reason, want[i].ip, want[i].action, want[i].reason)
+		}
+	}
+
+	access, _ := memory.Access("example.com")
+	if wantAccess := []AccessRule{{Ip: "198.51.100.1", Action: IP_BAN}}; !reflect.DeepEqual(access, wantAccess) {
+		t.Errorf("access %v, want %v", access, wantAccess)
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("log has %d lines, want %d", len(lines), len(want))
+	}
+	var logged BanDecision
+	if err := json.Unmarshal([]byte(lines[0]), &logged); err != nil || logged.Ip != "198.51.100.1" || logged.Action != BAN_BAN {
+		t.Errorf("log line %s: %v", lines[0], err)
+	}
+
+	decisions, err = autoban.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decisions[0].Action != BAN_SKIP || decisions[0].Reason != "already banned" {
+		t.Errorf("second check: %+v", decisions[0])
+	}
+}
+
+func TestAutoBanDryRun(t *testing.T) {
+	memory := newTestMemory()
+	memory.AddRecentIp("example.com", Ip{Ip: "198.51.100.1", Classification: "threat", Hits: "50"})
+
+	autoban := &AutoBan{Client: memory, Domain: "example.com", Rules: BanRules{MinHits: 10}, DryRun: true}
+	decisions, err := autoban.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decisions) != 1 || decisions[0].Action != BAN_BAN || !decisions[0].DryRun {
+		t.Errorf("decisions %+v", decisions)
+	}
+	if access, _ := memory.Access("example.com"); len(access) > 0 {
+		t.Errorf("dry run banned %v", access)
+	}
+}
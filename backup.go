@@ -0,0 +1,159 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	BACKUP_FORMAT  string = "cloudflare-zone-backup"
+	BACKUP_VERSION int    = 1
+)
+
+/* Zone backup archive */
+
+type ZoneBackup struct {
+	Format   string       `json:"format"`
+	Version  int          `json:"version"`
+	Zone     string       `json:"zone"`
+	Created  time.Time    `json:"created"`
+	Records  []Record     `json:"records"`
+	Settings Settings     `json:"settings"`
+	Access   []AccessRule `json:"access"`
+}
+
+// AccessRule is an IP access entry, Action being "ban" or "wl".
+type AccessRule struct {
+	Ip     string `json:"ip"`
+	Action string `json:"action"`
+}
+
+type RestoreReport struct {
+	Plan     Plan     `json:"plan"`
+	Settings []string `json:"settings"`
+	Access   int      `json:"access"`
+}
+
+// BackupZone captures the records and settings of domain. The API has no
+// way to list IP access rules, so the known rules are passed in by the
+// caller and stored as they are.
+func BackupZone(client API, domain string, access []AccessRule) (ZoneBackup, error) {
+	records, err := client.GetDnsRecords(domain)
+	if err != nil {
+		return ZoneBackup{}, err
+	}
+	settings, err := client.GetZoneSettings(domain)
+	if err != nil {
+		return ZoneBackup{}, err
+	}
+	if len(settings.Response.Result) == 0 {
+		return ZoneBackup{}, fmt.Errorf("%s: no zone settings returned", domain)
+	}
+
+	return ZoneBackup{
+		Format:   BACKUP_FORMAT,
+		Version:  BACKUP_VERSION,
+		Zone:     domain,
+		Created:  time.Now().UTC(),
+		Records:  records.Response.Objs,
+		Settings: settings.Response.Result[0],
+		Access:   access,
+	}, nil
+}
+
+func WriteBackup(w io.Writer, backup ZoneBackup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(backup)
+}
+
+func ReadBackup(r io.Reader) (ZoneBackup, error) {
+	backup := ZoneBackup{}
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return ZoneBackup{}, err
+	}
+	if backup.Format != BACKUP_FORMAT {
+		return ZoneBackup{}, fmt.Errorf("not a zone backup (format %q)", backup.Format)
+	}
+	if backup.Version > BACKUP_VERSION {
+		return ZoneBackup{}, fmt.Errorf("backup version %d is newer than supported version %d", backup.Version, BACKUP_VERSION)
+	}
+	return backup, nil
+}
+
+func SaveBackup(path string, backup ZoneBackup) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteBackup(file, backup); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func LoadBackup(path string) (ZoneBackup, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ZoneBackup{}, err
+	}
+	defer file.Close()
+	return ReadBackup(file)
+}
+
+// RestoreZone reconciles the zone of backup with its content: records are
+// created, edited or deleted to match, settings that differ are set again
+// and access rules are reapplied.
+func RestoreZone(client API, backup ZoneBackup) (RestoreReport, error) {
+	report := RestoreReport{}
+
+	desired := DesiredZone{Zone: backup.Zone}
+	for _, record := range backup.Records {
+		desired.Records = append(desired.Records, record.Input())
+	}
+	plan, err := PlanDnsSync(client, desired)
+	if err != nil {
+		return report, err
+	}
+	report.Plan = plan
+	if err := ApplyPlan(client, plan); err != nil {
+		return report, err
+	}
+
+	changed, err := ApplyZoneConfig(client, backup.Zone, backup.Settings.Typed())
+	report.Settings = changed
+	if err != nil {
+		return report, err
+	}
+
+	for _, rule := range backup.Access {
+		switch rule.Action {
+		case IP_BAN:
+			_, err = client.DenyIP(backup.Zone, rule.Ip)
+		case IP_ALLOW:
+			_, err = client.AllowIP(backup.Zone, rule.Ip)
+		default:
+			err = fmt.Errorf("unknown access action %q", rule.Action)
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s %s: %s", rule.Action, rule.Ip, err)
+		}
+		report.Access++
+	}
+	return report, nil
+}
+
+func (this RestoreReport) String() string {
+	s := this.Plan.String()
+	s += "settings restored: " + strconv.Itoa(len(this.Settings))
+	for _, name := range this.Settings {
+		s += " " + name
+	}
+	s += "\naccess rules applied: " + strconv.Itoa(this.Access) + "\n"
+	return s
+}
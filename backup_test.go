@@ -0,0 +1,69 @@
+package cloudflare
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBackupRestoreOnMemory(t *testing.T) {
+	memory := newTestMemory()
+	memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www", "content": "192.0.2.1", "ttl": "300"})
+	memory.NewDnsRecord("example.com", map[string]string{"type": "TXT", "name": "@", "content": "v=spf1 -all"})
+	memory.UpdateSecurityLevel("example.com", SEC_LVL_HIGH)
+	memory.DenyIP("example.com", "198.51.100.7")
+
+	access, _ := memory.Access("example.com")
+	backup, err := BackupZone(memory, "example.com", access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := memoryRecords(t, memory, "example.com")
+
+	// Restore what was written, not the value in memory.
+	var b bytes.Buffer
+	if err := WriteBackup(&b, backup); err != nil {
+		t.Fatal(err)
+	}
+	backup, err = ReadBackup(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestMemory()
+	restored.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "stale", "content": "192.0.2.9"})
+	report, err := RestoreZone(restored, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Access != 1 {
+		t.Errorf("access rules applied %d, want 1", report.Access)
+	}
+	if got := memoryRecords(t, restored, "example.com"); !reflect.DeepEqual(got, records) {
+		t.Errorf("records\n got %+v\nwant %+v", got, records)
+	}
+	config, _ := GetZoneConfig(restored, "example.com")
+	if *config.SecurityLevel != SEC_LVL_HIGH {
+		t.Errorf("security level %s", *config.SecurityLevel)
+	}
+	if rules, _ := restored.Access("example.com"); !reflect.DeepEqual(rules, access) {
+		t.Errorf("access %v, want %v", rules, access)
+	}
+}
+
+func TestReadBackupErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"format": "other", "version": 1}`, `not a zone backup (format "other")`},
+		{`{"format": "` + BACKUP_FORMAT + `", "version": 99}`, "backup version 99 is newer than supported version"},
+	}
+	for _, test := range tests {
+		_, err := ReadBackup(strings.NewReader(test.input))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.input, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	BATCH_CREATE string = "create"
+	BATCH_EDIT   string = "edit"
+	BATCH_DELETE string = "delete"
+)
+
+var ErrSkipped = errors.New("skipped after an earlier failure")
+
+/* Bulk record operations */
+
+type BatchOp struct {
+	Action string            `json:"action"`
+	Domain string            `json:"domain"`
+	Id     string            `json:"id"`
+	Values map[string]string `json:"values"`
+}
+
+type BatchResult struct {
+	Op     BatchOp `json:"op"`
+	Record Record  `json:"record"`
+	Err    error   `json:"-"`
+}
+
+type BatchOptions struct {
+	Workers     int
+	StopOnError bool
+}
+
+// BatchError lists the operations that failed and, with StopOnError, the
+// ones skipped after the failure.
+type BatchError struct {
+	Total    int
+	Failures []BatchResult
+	Skipped  []BatchResult
+}
+
+func (this *BatchError) Error() string {
+	var messages []string
+	for _, failure := range this.Failures {
+		messages = append(messages, fmt.Sprintf("%s %s: %s", failure.Op.Action, batchTarget(failure.Op), failure.Err))
+	}
+	skipped := ""
+	if len(this.Skipped) > 0 {
+		skipped = fmt.Sprintf(", %d skipped", len(this.Skipped))
+	}
+	return fmt.Sprintf("%d of %d operations failed%s: %s", len(this.Failures), this.Total, skipped, strings.Join(messages, "; "))
+}
+
+func batchTarget(op BatchOp) string {
+	if op.Id != "" {
+		return op.Domain + "/" + op.Id
+	}
+	return op.Domain + "/" + op.Values["name"]
+}
+
+// Batch runs record operations on a pool of workers, paced by the client's
+// rate limit. Results are returned in the order of ops. With StopOnError no
+// new operation starts after a failure and the remaining ones are marked
+// ErrSkipped. The error is a *BatchError when any operation failed.
+func Batch(client RecordsAPI, ops []BatchOp, options BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchResult{Op: op, Err: ErrSkipped}
+	}
+
+	runParallel(len(ops), options.Workers, options.StopOnError, func(i int) error {
+		record, err := runBatchOp(client, ops[i])
+		results[i].Record = record
+		results[i].Err = err
+		return err
+	})
+
+	failed := &BatchError{Total: len(ops)}
+	for _, result := range results {
+		switch {
+		case result.Err == ErrSkipped:
+			failed.Skipped = append(failed.Skipped, result)
+		case result.Err != nil:
+			failed.Failures = append(failed.Failures, result)
+		}
+	}
+	if len(failed.Failures) > 0 {
+		return results, failed
+	}
+	return results, nil
+}
+
+func runBatchOp(client RecordsAPI, op BatchOp) (Record, error) {
+	switch op.Action {
+	case BATCH_CREATE:
+		response, err := client.NewDnsRecord(op.Domain, op.Values)
+		return response.Response.Rec, err
+	case BATCH_EDIT:
+		response, err := client.EditDnsRecord(op.Domain, op.Id, op.Values)
+		return response.Response.Rec, err
+	case BATCH_DELETE:
+		_, err := client.DeleteDnsRecord(op.Domain, op.Id)
+		return Record{}, err
+	}
+	return Record{}, fmt.Errorf("unknown batch action %q", op.Action)
+}
+
+// runParallel calls fn for 0..n-1 on at most workers goroutines. With
+// stopOnError, indexes not yet started when fn fails are not run.
+func runParallel(n, workers int, stopOnError bool, fn func(i int) error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var mu sync.Mutex
+	stopped := false
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil && stopOnError {
+					mu.Lock()
+					stopped = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		stop := stopped
+		mu.Unlock()
+		if stop {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
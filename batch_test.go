@@ -0,0 +1,65 @@
+package cloudflare
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBatchOnMemory(t *testing.T) {
+	memory := newTestMemory()
+
+	results, err := Batch(memory, []BatchOp{
+		{Action: BATCH_CREATE, Domain: "example.com", Values: map[string]string{"type": "A", "name": "a", "content": "192.0.2.1"}},
+		{Action: BATCH_CREATE, Domain: "example.org", Values: map[string]string{"type": "A", "name": "a", "content": "192.0.2.1"}},
+	}, BatchOptions{Workers: 2})
+	batchErr, ok := err.(*BatchError)
+	if !ok || len(batchErr.Failures) != 1 || batchErr.Failures[0].Op.Domain != "example.org" || len(batchErr.Skipped) > 0 {
+		t.Fatalf("error %v, want one failure on example.org", err)
+	}
+	id := results[0].Record.Id
+
+	_, err = Batch(memory, []BatchOp{
+		{Action: BATCH_EDIT, Domain: "example.com", Id: id, Values: map[string]string{"content": "192.0.2.2"}},
+	}, BatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RecordInput{{Name: "a.example.com", Type: "A", Content: "192.0.2.2"}}
+	if got := memoryRecords(t, memory, "example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("records\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestBatchStopOnError(t *testing.T) {
+	memory := newTestMemory()
+
+	var ops []BatchOp
+	for _, domain := range []string{"example.com", "example.org", "example.com", "example.com"} {
+		ops = append(ops, BatchOp{Action: BATCH_CREATE, Domain: domain, Values: map[string]string{"type": "A", "name": "a", "content": "192.0.2.1"}})
+	}
+	results, err := Batch(memory, ops, BatchOptions{Workers: 1, StopOnError: true})
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("error %v, want a *BatchError", err)
+	}
+
+	// The operation dispatched while the failure runs may still run, the
+	// last one never does.
+	if len(batchErr.Failures) != 1 || len(batchErr.Skipped) == 0 || results[3].Err != ErrSkipped {
+		t.Fatalf("failures %+v, skipped %+v", batchErr.Failures, batchErr.Skipped)
+	}
+	prefix := fmt.Sprintf("1 of 4 operations failed, %d skipped: create example.org/a: ", len(batchErr.Skipped))
+	if !strings.HasPrefix(err.Error(), prefix) {
+		t.Errorf("error %q, want prefix %q", err, prefix)
+	}
+
+	results, err = Batch(memory, ops[:2], BatchOptions{Workers: 2})
+	if err == nil || !strings.HasPrefix(err.Error(), "1 of 2 operations failed: ") {
+		t.Errorf("error %v without skipped operations", err)
+	}
+	if results[0].Err != nil || results[0].Record.Id == "" {
+		t.Errorf("result %+v", results[0])
+	}
+}
@@ -0,0 +1,174 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/* Read-through cache of API responses */
+
+// DefaultCacheTtls are the cached actions and their TTL used by
+// EnableCache when no TTLs are given.
+var DefaultCacheTtls = map[string]time.Duration{
+	"zone_load_multi": 5 * time.Minute,
+	"rec_load_all":    time.Minute,
+}
+
+// cacheKey identifies a request by its action, its zone and all of its
+// parameters but the credentials.
+type cacheKey struct {
+	action string
+	zone   string
+	query  string
+}
+
+func newCacheKey(values url.Values) cacheKey {
+	params := url.Values{}
+	for name, value := range values {
+		if name != "tkn" && name != "email" {
+			params[name] = value
+		}
+	}
+	return cacheKey{action: values.Get("a"), zone: values.Get("z"), query: params.Encode()}
+}
+
+type cacheEntry struct {
+	content []byte
+	expires time.Time
+}
+
+type responseCache struct {
+	mu      sync.Mutex
+	ttls    map[string]time.Duration
+	entries map[cacheKey]cacheEntry
+}
+
+// EnableCache caches the successful responses of the actions of ttls, keyed
+// by all their parameters, for their TTL. A nil ttls uses DefaultCacheTtls.
+// Record changes made through the client drop the cached responses of their
+// zone, other changes also drop the zone list.
+func (this *Cloudflare) EnableCache(ttls map[string]time.Duration) {
+	if ttls == nil {
+		ttls = DefaultCacheTtls
+	}
+	cache := &responseCache{
+		ttls:    make(map[string]time.Duration),
+		entries: make(map[cacheKey]cacheEntry),
+	}
+	for action, ttl := range ttls {
+		if ttl > 0 {
+			cache.ttls[action] = ttl
+		}
+	}
+	this.cache = cache
+}
+
+func (this *Cloudflare) DisableCache() {
+	this.cache = nil
+}
+
+// InvalidateZone drops the cached responses for zone.
+func (this *Cloudflare) InvalidateZone(zone string) {
+	if this.cache != nil {
+		this.cache.invalidate(func(key cacheKey) bool {
+			return key.zone == zone
+		})
+	}
+}
+
+// InvalidateAction drops the cached responses of action for every zone.
+func (this *Cloudflare) InvalidateAction(action string) {
+	if this.cache != nil {
+		this.cache.invalidate(func(key cacheKey) bool {
+			return key.action == action
+		})
+	}
+}
+
+// InvalidateCache drops every cached response.
+func (this *Cloudflare) InvalidateCache() {
+	if this.cache != nil {
+		this.cache.invalidate(func(cacheKey) bool {
+			return true
+		})
+	}
+}
+
+func (this *responseCache) get(key cacheKey) ([]byte, bool) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	entry, ok := this.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(this.entries, key)
+		return nil, false
+	}
+	return entry.content, true
+}
+
+// put stores content when the action of key is cached and the response is
+// not an API error.
+func (this *responseCache) put(key cacheKey, content []byte) {
+	ttl, ok := this.ttls[key.action]
+	if !ok {
+		return
+	}
+	result := struct {
+		Result string `json:"result"`
+	}{}
+	if err := json.Unmarshal(content, &result); err != nil || result.Result == "error" {
+		return
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.entries[key] = cacheEntry{content: content, expires: time.Now().Add(ttl)}
+}
+
+func (this *responseCache) invalidate(match func(cacheKey) bool) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for key := range this.entries {
+		if match(key) {
+			delete(this.entries, key)
+		}
+	}
+}
+
+func (this *Cloudflare) fromCache(values url.Values) ([]byte, bool) {
+	if this.cache == nil {
+		return nil, false
+	}
+	content, ok := this.cache.get(newCacheKey(values))
+	if ok && this.Debug {
+		log.Printf("cache hit: %s %s", values.Get("a"), values.Get("z"))
+	}
+	return content, ok
+}
+
+// toCache stores the response to values, or drops the cached responses
+// that a mutating action makes stale.
+func (this *Cloudflare) toCache(values url.Values, content []byte, err error) {
+	if this.cache == nil {
+		return
+	}
+	action, zone := values.Get("a"), values.Get("z")
+	switch {
+	case isMutating(action):
+		this.cache.invalidate(func(key cacheKey) bool {
+			return key.zone == zone || (!isRecordAction(action) && key.action == "zone_load_multi")
+		})
+	case err == nil:
+		this.cache.put(newCacheKey(values), content)
+	}
+}
+
+func isRecordAction(action string) bool {
+	return action == "rec_new" || action == "rec_edit" || action == "rec_delete"
+}
@@ -0,0 +1,125 @@
+package cloudflare
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const cacheTestRecords = `{"result":"success","response":{"objs":[{"rec_id":"1","name":"www.example.com","type":"A","content":"192.0.2.1"}]}}`
+
+func TestCacheKeyedByParameters(t *testing.T) {
+	client, server := newTestClient(t, func(url.Values) string {
+		return `{"result":"success","response":{}}`
+	})
+	client.EnableCache(map[string]time.Duration{"stats": time.Minute, "rec_load_all": time.Minute})
+
+	for _, interval := range []string{"20", "30", "20"} {
+		if _, err := client.GetDomainStats("example.com", interval); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, domain := range []string{"example.com", "example.org", "example.com"} {
+		if _, err := client.GetDnsRecords(domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"stats", "stats", "rec_load_all", "rec_load_all"}
+	if actions := server.actions(); !reflect.DeepEqual(actions, want) {
+		t.Errorf("requests %v, want %v", actions, want)
+	}
+
+	// The credentials are not part of the key.
+	client.ApiKey = "other-key"
+	if _, err := client.GetDomainStats("example.com", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if actions := server.actions(); len(actions) != 4 {
+		t.Errorf("requests %v, want %v", actions, want)
+	}
+}
+
+func TestCacheRecordChangesInvalidateZone(t *testing.T) {
+	client, server := newTestClient(t, func(values url.Values) string {
+		switch values.Get("a") {
+		case "rec_load_all":
+			return cacheTestRecords
+		case "rec_delete":
+			return `{"result":"success"}`
+		}
+		return `{"result":"success","response":{"rec":{"obj":{"rec_id":"1"}}}}`
+	})
+	client.EnableCache(nil)
+
+	changes := []func() error{
+		func() error {
+			_, err := client.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www", "content": "192.0.2.1"})
+			return err
+		},
+		func() error {
+			_, err := client.EditDnsRecord("example.com", "1", map[string]string{"content": "192.0.2.2"})
+			return err
+		},
+		func() error {
+			_, err := client.DeleteDnsRecord("example.com", "1")
+			return err
+		},
+	}
+	for _, change := range changes {
+		for i := 0; i < 2; i++ {
+			if _, err := client.GetDnsRecords("example.com"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.GetDnsRecords("example.org"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.GetDnsRecords("example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	var loads []string
+	for _, values := range server.requests {
+		if values.Get("a") == "rec_load_all" {
+			loads = append(loads, values.Get("z"))
+		}
+	}
+	// example.org stays cached, example.com is reloaded after each change.
+	want := []string{"example.com", "example.org", "example.com", "example.com", "example.com"}
+	if !reflect.DeepEqual(loads, want) {
+		t.Errorf("loaded %v, want %v", loads, want)
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	fail := true
+	client, server := newTestClient(t, func(url.Values) string {
+		if fail {
+			return `{"result":"error","msg":"rate limited"}`
+		}
+		return cacheTestRecords
+	})
+	client.EnableCache(nil)
+
+	if _, err := client.GetDnsRecords("example.com"); err == nil {
+		t.Fatal("error response accepted")
+	}
+	fail = false
+	for i := 0; i < 2; i++ {
+		records, err := client.GetDnsRecords("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records.Response.Objs) != 1 {
+			t.Errorf("records %+v", records.Response.Objs)
+		}
+	}
+	if actions := server.actions(); len(actions) != 2 {
+		t.Errorf("requests %v, want the error and one load", actions)
+	}
+}
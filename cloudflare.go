@@ -3,37 +3,94 @@ package cloudflare
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	CF_API_URL string = "https://www.cloudflare.com/api_json.html"
+	CF_API_URL     string        = "https://www.cloudflare.com/api_json.html"
+	CF_RATE_LIMIT  int           = 1200
+	CF_RATE_PERIOD time.Duration = 5 * time.Minute
 )
 
 type Cloudflare struct {
 	ApiKey string
 	Email  string
+	ApiUrl string
 	Domain string
 	Debug  bool
+	DryRun bool
+
+	Audit    io.Writer
+	Operator string
+	Journal  *UndoJournal
+
+	limiter  *rateLimiter
+	cache    *responseCache
+	resolver zoneResolver
+	auditMu  sync.Mutex
 }
 
+// Connect returns a client limited to the API budget of CF_RATE_LIMIT
+// requests per CF_RATE_PERIOD, see SetRateLimit.
 func Connect(apikey, email string, debug bool) *Cloudflare {
-	return &Cloudflare{
+	cf := &Cloudflare{
 		ApiKey: apikey,
 		Email:  email,
+		ApiUrl: CF_API_URL,
 		Debug:  debug,
 	}
+	cf.SetRateLimit(CF_RATE_LIMIT, CF_RATE_PERIOD)
+	return cf
+}
+
+// SetRateLimit spreads requests so that at most requests are sent per
+// period. A zero value removes the limit.
+func (this *Cloudflare) SetRateLimit(requests int, period time.Duration) {
+	if requests <= 0 || period <= 0 {
+		this.limiter = nil
+		return
+	}
+	this.limiter = &rateLimiter{interval: period / time.Duration(requests)}
+}
+
+func (this *Cloudflare) apiUrl() string {
+	if this.ApiUrl == "" {
+		return CF_API_URL
+	}
+	return this.ApiUrl
 }
 
 func (this *Cloudflare) sendRequest(values url.Values) ([]byte, error) {
+	if this.DryRun && isMutating(values.Get("a")) {
+		return dryRun(this.apiUrl(), values)
+	}
+	if content, ok := this.fromCache(values); ok {
+		return content, nil
+	}
+	if this.limiter != nil {
+		this.limiter.wait()
+	}
+
+	content, err := this.post(values)
+	if this.Audit != nil && isMutating(values.Get("a")) {
+		this.audit(values, content, err)
+	}
+	this.toCache(values, content, err)
+	return content, err
+}
+
+func (this *Cloudflare) post(values url.Values) ([]byte, error) {
 	values.Set("tkn", this.ApiKey)
 	values.Set("email", this.Email)
 
-	response, err := http.PostForm(CF_API_URL, values)
+	response, err := http.PostForm(this.apiUrl(), values)
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -56,6 +113,9 @@ func (this *Cloudflare) GetDomainStats(domain, interval string) (RootStats, erro
 	values.Set("interval", interval)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootStats{}, err
+	}
 
 	data := RootStats{}
 	err = json.Unmarshal(response, &data)
@@ -74,6 +134,9 @@ func (this *Cloudflare) GetDomainsList() (RootZones, error) {
 	values.Set("a", "zone_load_multi")
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootZones{}, err
+	}
 
 	data := RootZones{}
 	err = json.Unmarshal(response, &data)
@@ -93,6 +156,9 @@ func (this *Cloudflare) GetDnsRecords(domain string) (RootDnsRecords, error) {
 	values.Set("a", "rec_load_all")
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootDnsRecords{}, err
+	}
 
 	data := RootDnsRecords{}
 	err = json.Unmarshal(response, &data)
@@ -115,6 +181,9 @@ func (this *Cloudflare) NewDnsRecord(domain string, values map[string]string) (R
 	}
 
 	response, err := this.sendRequest(args)
+	if err != nil {
+		return RootNewRecord{}, err
+	}
 
 	data := RootNewRecord{}
 	err = json.Unmarshal(response, &data)
@@ -129,6 +198,18 @@ func (this *Cloudflare) NewDnsRecord(domain string, values map[string]string) (R
 }
 
 func (this *Cloudflare) EditDnsRecord(domain, id string, values map[string]string) (RootEditRecord, error) {
+	entry, err := this.journalEntry(domain, id, JOURNAL_EDIT)
+	if err != nil {
+		return RootEditRecord{}, err
+	}
+	data, err := this.editDnsRecord(domain, id, values)
+	if err == nil {
+		this.journal(entry)
+	}
+	return data, err
+}
+
+func (this *Cloudflare) editDnsRecord(domain, id string, values map[string]string) (RootEditRecord, error) {
 	args := url.Values{}
 	args.Set("z", domain)
 	args.Set("a", "rec_edit")
@@ -138,6 +219,9 @@ func (this *Cloudflare) EditDnsRecord(domain, id string, values map[string]strin
 	}
 
 	response, err := this.sendRequest(args)
+	if err != nil {
+		return RootEditRecord{}, err
+	}
 
 	data := RootEditRecord{}
 	err = json.Unmarshal(response, &data)
@@ -152,11 +236,27 @@ func (this *Cloudflare) EditDnsRecord(domain, id string, values map[string]strin
 }
 
 func (this *Cloudflare) DeleteDnsRecord(domain, id string) (Root, error) {
+	entry, err := this.journalEntry(domain, id, JOURNAL_DELETE)
+	if err != nil {
+		return Root{}, err
+	}
+	data, err := this.deleteDnsRecord(domain, id)
+	if err == nil {
+		this.journal(entry)
+	}
+	return data, err
+}
+
+func (this *Cloudflare) deleteDnsRecord(domain, id string) (Root, error) {
 	values := url.Values{}
 	values.Set("z", domain)
+	values.Set("a", "rec_delete")
 	values.Set("id", id)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -184,47 +284,83 @@ func (this *Cloudflare) SetProxyStatus(domain, id string, status bool) (RootEdit
 	return response, err
 }
 
+// SetSecurityLevel sets the security level of domain. UpdateSecurityLevel
+// returns the same change as a typed result.
 func (this *Cloudflare) SetSecurityLevel(domain, level string) (RootZones, error) {
+	data, err := this.UpdateSecurityLevel(domain, level)
+	return data.Zones(), err
+}
+
+// UpdateSecurityLevel sets the security level of domain and returns the
+// updated zone.
+func (this *Cloudflare) UpdateSecurityLevel(domain, level string) (RootSecLevel, error) {
 	values := url.Values{}
 	values.Set("z", domain)
 	values.Set("a", "sec_lvl")
 	values.Set("v", level)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootSecLevel{}, err
+	}
 
-	data := RootZones{}
+	data := RootSecLevel{}
 	err = json.Unmarshal(response, &data)
 	if err != nil {
-		return RootZones{}, err
+		return RootSecLevel{}, err
 	}
 	if data.Result == "error" {
 		err = errors.New(data.Message)
-		return RootZones{}, err
+		return RootSecLevel{}, err
 	}
 	return data, nil
 }
 
+// SetCacheLevel sets the cache level of domain. UpdateCacheLevel returns
+// the same change as a typed result.
 func (this *Cloudflare) SetCacheLevel(domain, level string) (RootZones, error) {
+	data, err := this.UpdateCacheLevel(domain, level)
+	return data.Zones(), err
+}
+
+// UpdateCacheLevel sets the cache level of domain and returns the updated
+// zone.
+func (this *Cloudflare) UpdateCacheLevel(domain, level string) (RootCacheLevel, error) {
 	values := url.Values{}
 	values.Set("z", domain)
 	values.Set("a", "cache_lvl")
 	values.Set("v", level)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootCacheLevel{}, err
+	}
 
-	data := RootZones{}
+	data := RootCacheLevel{}
 	err = json.Unmarshal(response, &data)
 	if err != nil {
-		return RootZones{}, err
+		return RootCacheLevel{}, err
 	}
 	if data.Result == "error" {
 		err = errors.New(data.Message)
-		return RootZones{}, err
+		return RootCacheLevel{}, err
 	}
 	return data, nil
 }
 
-func (this *Cloudflare) SetDevMode(domain string, enable bool) (RootZones, error) {
+// SetDevMode turns development mode on or off and returns when it expires.
+// The expiry is zero when turning it off or in dry-run.
+func (this *Cloudflare) SetDevMode(domain string, enable bool) (time.Time, error) {
+	data, err := this.ToggleDevMode(domain, enable)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return data.Response.Expires(), nil
+}
+
+// ToggleDevMode turns development mode on or off and returns the updated
+// zone along with the expiry.
+func (this *Cloudflare) ToggleDevMode(domain string, enable bool) (RootDevMode, error) {
 	dev := "0"
 	if enable {
 		dev = "1"
@@ -236,15 +372,18 @@ func (this *Cloudflare) SetDevMode(domain string, enable bool) (RootZones, error
 	values.Set("v", dev)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootDevMode{}, err
+	}
 
-	data := RootZones{}
+	data := RootDevMode{}
 	err = json.Unmarshal(response, &data)
 	if err != nil {
-		return RootZones{}, err
+		return RootDevMode{}, err
 	}
 	if data.Result == "error" {
 		err = errors.New(data.Message)
-		return RootZones{}, err
+		return RootDevMode{}, err
 	}
 	return data, nil
 }
@@ -256,6 +395,9 @@ func (this *Cloudflare) PurgeCache(domain string) (RootPurgeCache, error) {
 	values.Set("v", "1")
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootPurgeCache{}, err
+	}
 
 	data := RootPurgeCache{}
 	err = json.Unmarshal(response, &data)
@@ -276,6 +418,9 @@ func (this *Cloudflare) PurgeFile(domain, url_file string) (RootPurgeFile, error
 	values.Set("url", url_file)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootPurgeFile{}, err
+	}
 
 	data := RootPurgeFile{}
 	err = json.Unmarshal(response, &data)
@@ -296,6 +441,9 @@ func (this *Cloudflare) LookupIp(domain, ip string) (RootLookupIp, error) {
 	values.Set("ip", ip)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootLookupIp{}, err
+	}
 
 	data := RootLookupIp{}
 	err = json.Unmarshal(response, &data)
@@ -316,6 +464,9 @@ func (this *Cloudflare) DenyIP(domain, ip string) (RootModIp, error) {
 	values.Set("key", ip)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootModIp{}, err
+	}
 
 	data := RootModIp{}
 	err = json.Unmarshal(response, &data)
@@ -336,6 +487,9 @@ func (this *Cloudflare) ForgetIP(domain, ip string) (RootModIp, error) {
 	values.Set("key", ip)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootModIp{}, err
+	}
 
 	data := RootModIp{}
 	err = json.Unmarshal(response, &data)
@@ -356,6 +510,9 @@ func (this *Cloudflare) AllowIP(domain, ip string) (RootModIp, error) {
 	values.Set("key", ip)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootModIp{}, err
+	}
 
 	data := RootModIp{}
 	err = json.Unmarshal(response, &data)
@@ -380,6 +537,9 @@ func (this *Cloudflare) ToggleMirage2(domain string, toggle bool) (Root, error)
 	values.Set("v", status)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -400,6 +560,9 @@ func (this *Cloudflare) Minify(domain, state string) (Root, error) {
 	values.Set("v", state)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -420,6 +583,9 @@ func (this *Cloudflare) SetRocketLoader(domain, state string) (Root, error) {
 	values.Set("v", state)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -444,6 +610,9 @@ func (this *Cloudflare) ToggleIpv46(domain string, toggle bool) (Root, error) {
 	values.Set("v", status)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -457,13 +626,26 @@ func (this *Cloudflare) ToggleIpv46(domain string, toggle bool) (Root, error) {
 	return data, nil
 }
 
+// Snapshot takes a snapshot of the zone. An empty zoneid is resolved from
+// the domain with ZoneId.
 func (this *Cloudflare) Snapshot(domain, zoneid string) (Root, error) {
+	if zoneid == "" {
+		id, err := this.ZoneId(domain)
+		if err != nil {
+			return Root{}, err
+		}
+		zoneid = id
+	}
+
 	values := url.Values{}
 	values.Set("z", domain)
 	values.Set("a", "zone_grab")
 	values.Set("zid", zoneid)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return Root{}, err
+	}
 
 	data := Root{}
 	err = json.Unmarshal(response, &data)
@@ -483,6 +665,9 @@ func (this *Cloudflare) GetZoneSettings(domain string) (RootZoneSettings, error)
 	values.Set("a", "zone_settings")
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootZoneSettings{}, err
+	}
 
 	data := RootZoneSettings{}
 	err = json.Unmarshal(response, &data)
@@ -503,6 +688,9 @@ func (this *Cloudflare) GetActiveZones(domain string, zones ...string) (RootZone
 	values.Set("zones", strings.Join(zones, ","))
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootZonesCheck{}, err
+	}
 
 	data := RootZonesCheck{}
 	err = json.Unmarshal(response, &data)
@@ -525,6 +713,9 @@ func (this *Cloudflare) GetRecentIps(domain, hours, class, geo string) (RootZone
 	values.Set("geo", geo)
 
 	response, err := this.sendRequest(values)
+	if err != nil {
+		return RootZoneIps{}, err
+	}
 
 	data := RootZoneIps{}
 	err = json.Unmarshal(response, &data)
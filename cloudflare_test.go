@@ -0,0 +1,48 @@
+package cloudflare
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// testServer stands in for the API: respond gets the form values of each
+// request and returns the JSON body to answer with.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// newTestClient returns a client talking to a testServer.
+func newTestClient(t *testing.T, respond func(values url.Values) string) (*Cloudflare, *testServer) {
+	server := &testServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		server.mu.Lock()
+		server.requests = append(server.requests, r.PostForm)
+		server.mu.Unlock()
+		io.WriteString(w, respond(r.PostForm))
+	}))
+	t.Cleanup(server.Close)
+
+	return &Cloudflare{ApiKey: "secret-key", Email: "user@example.com", ApiUrl: server.URL}, server
+}
+
+// actions lists the actions requested so far.
+func (this *testServer) actions() []string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	var actions []string
+	for _, values := range this.requests {
+		actions = append(actions, values.Get("a"))
+	}
+	return actions
+}
@@ -199,7 +199,7 @@ type RootZoneIps struct {
 }
 
 type ZoneIps struct {
-	Ips Ip `json:"ips"`
+	Ips []Ip `json:"ips"`
 }
 
 type Ip struct {
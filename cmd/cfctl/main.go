@@ -0,0 +1,436 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gaelreyrol/cloudflare"
+)
+
+type command struct {
+	usage string
+	run   func(cf *cloudflare.Cloudflare, args []string) (interface{}, error)
+}
+
+var commands = map[string]command{
+	"zones list": {"", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetDomainsList()
+		return result.Response.Zones.Objs, err
+	}},
+	"zones check": {"<domain> <zone>...", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetActiveZones(args[0], args[1:]...)
+		return result.Response.Zones, err
+	}},
+	"zones snapshot": {"<domain> [zone id]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		zoneid := ""
+		if len(args) > 1 {
+			zoneid = args[1]
+		}
+		return cf.Snapshot(args[0], zoneid)
+	}},
+	"records list": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetDnsRecords(args[0])
+		return result.Response.Objs, err
+	}},
+	"records create": {"<domain> key=value...", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		values, err := keyValues(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		result, err := cf.NewDnsRecord(args[0], values)
+		return result.Response.Rec, err
+	}},
+	"records edit": {"<domain> <id> key=value...", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		values, err := keyValues(args[2:])
+		if err != nil {
+			return nil, err
+		}
+		result, err := cf.EditDnsRecord(args[0], args[1], values)
+		return result.Response.Rec, err
+	}},
+	"records delete": {"<domain> <id>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.DeleteDnsRecord(args[0], args[1])
+	}},
+	"records import": {"<domain> <zone file>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		file, err := os.Open(args[1])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		zone, err := cloudflare.ParseZoneFile(file, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := zone.Preview(os.Stderr); err != nil {
+			return nil, err
+		}
+		return cf.ImportZoneFile(args[0], zone)
+	}},
+	"records export": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return nil, cf.ExportZoneFile(os.Stdout, args[0])
+	}},
+	"records watch": {"<domain> [interval]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		watcher := cloudflare.RecordWatcher{Client: cf, Zone: args[0]}
+		if len(args) > 1 {
+			interval, err := time.ParseDuration(args[1])
+			if err != nil {
+				return nil, err
+			}
+			watcher.Interval = interval
+		}
+		for event := range watcher.Watch(make(chan struct{})) {
+			fmt.Println(event)
+		}
+		return nil, nil
+	}},
+	"records sync": {"<desired zone file> [apply]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		desired, err := cloudflare.LoadDesiredZone(args[0])
+		if err != nil {
+			return nil, err
+		}
+		plan, err := cloudflare.PlanDnsSync(cf, desired)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(os.Stderr, plan)
+		if len(args) > 1 && args[1] == "apply" {
+			return nil, cloudflare.ApplyPlan(cf, plan)
+		}
+		return nil, nil
+	}},
+	"proxy on": {"<domain> <id>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.SetProxyStatus(args[0], args[1], true)
+		return result.Response.Rec, err
+	}},
+	"proxy off": {"<domain> <id>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.SetProxyStatus(args[0], args[1], false)
+		return result.Response.Rec, err
+	}},
+	"security-level": {"<domain> help|high|med|low|eoff", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.UpdateSecurityLevel(args[0], args[1])
+		return result.Response.Zone, err
+	}},
+	"cache-level": {"<domain> agg|basic", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.UpdateCacheLevel(args[0], args[1])
+		return result.Response.Zone, err
+	}},
+	"devmode on": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		expires, err := cf.SetDevMode(args[0], true)
+		if err == nil && !expires.IsZero() {
+			fmt.Fprintf(os.Stderr, "development mode on until %s\n", expires.Format(time.RFC1123))
+		}
+		return nil, err
+	}},
+	"devmode off": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		_, err := cf.SetDevMode(args[0], false)
+		return nil, err
+	}},
+	"devmode for": {"<domain> <duration>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		window, err := time.ParseDuration(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return nil, cf.DevModeWindow(args[0], window)
+	}},
+	"minify": {"<domain> 0-7", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.Minify(args[0], args[1])
+	}},
+	"rocket-loader": {"<domain> 0|a|m", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.SetRocketLoader(args[0], args[1])
+	}},
+	"ipv6 on": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.ToggleIpv46(args[0], true)
+	}},
+	"ipv6 off": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.ToggleIpv46(args[0], false)
+	}},
+	"mirage2 on": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.ToggleMirage2(args[0], true)
+	}},
+	"mirage2 off": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cf.ToggleMirage2(args[0], false)
+	}},
+	"purge": {"<domain> [url...]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		if len(args) == 1 {
+			result, err := cf.PurgeCache(args[0])
+			return result.Response, err
+		}
+		report, err := cloudflare.PurgeFiles(cf, args[0], args[1:], cloudflare.PurgeOptions{Workers: 4})
+		fmt.Fprint(os.Stderr, report)
+		return nil, err
+	}},
+	"purge deploy": {"<domain> <build dir> <manifest> <base url>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		report, err := cloudflare.PurgeDeploy(cf, args[0], cloudflare.Deploy{
+			Dir:       args[1],
+			Manifest:  args[2],
+			BaseUrl:   args[3],
+			CleanUrls: true,
+			Purge:     cloudflare.PurgeOptions{Workers: 4},
+		})
+		fmt.Fprint(os.Stderr, report)
+		return nil, err
+	}},
+	"purge sitemap": {"<domain> <sitemap> [path prefix] [since YYYY-MM-DD]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		filter := cloudflare.SitemapFilter{}
+		if len(args) > 2 {
+			filter.Prefix = args[2]
+		}
+		if len(args) > 3 {
+			since, err := time.Parse("2006-01-02", args[3])
+			if err != nil {
+				return nil, err
+			}
+			filter.Since = since
+		}
+		report, err := cloudflare.PurgeSitemap(cf, args[0], args[1], filter, cloudflare.PurgeOptions{Workers: 4})
+		fmt.Fprint(os.Stderr, report)
+		return nil, err
+	}},
+	"ip ban": {"<domain> <ip>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.DenyIP(args[0], args[1])
+		return result.Response, err
+	}},
+	"ip allow": {"<domain> <ip>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.AllowIP(args[0], args[1])
+		return result.Response, err
+	}},
+	"ip forget": {"<domain> <ip>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.ForgetIP(args[0], args[1])
+		return result.Response, err
+	}},
+	"ip lookup": {"<domain> <ip>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.LookupIp(args[0], args[1])
+		return result.Response, err
+	}},
+	"ip bulk": {"ban|wl|nul <domain> <file>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		file, err := os.Open(args[2])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		ips, err := cloudflare.ParseIpList(file)
+		if err != nil {
+			return nil, err
+		}
+		report, err := cloudflare.ApplyIpAccess(cf, args[1], args[0], ips, cloudflare.IpAccessOptions{
+			Workers: 4,
+			Lookup:  true,
+			Progress: func(done, total int) {
+				fmt.Fprintf(os.Stderr, "\r%d/%d", done, total)
+			},
+		})
+		fmt.Fprint(os.Stderr, "\n", report)
+		return nil, err
+	}},
+	"ip recent": {"<domain> <hours> <class> <geo>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetRecentIps(args[0], args[1], args[2], args[3])
+		return result.Response.Ips, err
+	}},
+	"stats": {"<domain> <interval>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetDomainStats(args[0], args[1])
+		return result.Response.Objs, err
+	}},
+	"settings": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		result, err := cf.GetZoneSettings(args[0])
+		return result.Response.Result, err
+	}},
+	"undo": {"[count]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		count := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q", args[0])
+			}
+			count = n
+		}
+		return cf.Undo(count)
+	}},
+	"settings show": {"<domain>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		return cloudflare.GetZoneConfig(cf, args[0])
+	}},
+	"settings apply": {"<domain> <settings file>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		data, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return nil, err
+		}
+		config, err := cloudflare.ParseZoneConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := cloudflare.ApplyZoneConfig(cf, args[0], config)
+		fmt.Fprintf(os.Stderr, "changed: %s\n", strings.Join(changed, ", "))
+		return nil, err
+	}},
+	"settings drift": {"<baseline file> [correct]", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		baselines, err := cloudflare.ParseBaselines(data)
+		if err != nil {
+			return nil, err
+		}
+		watcher := &cloudflare.DriftWatcher{
+			Client:    cf,
+			Baselines: baselines,
+			Correct:   len(args) > 1 && args[1] == "correct",
+			Report:    func(cloudflare.Drift) {},
+		}
+		return watcher.Check()
+	}},
+	"backup": {"<domain> <file>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		backup, err := cloudflare.BackupZone(cf, args[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		return nil, cloudflare.SaveBackup(args[1], backup)
+	}},
+	"restore": {"<file>", func(cf *cloudflare.Cloudflare, args []string) (interface{}, error) {
+		backup, err := cloudflare.LoadBackup(args[0])
+		if err != nil {
+			return nil, err
+		}
+		report, err := cloudflare.RestoreZone(cf, backup)
+		fmt.Fprint(os.Stderr, report)
+		return nil, err
+	}},
+}
+
+func main() {
+	key := flag.String("key", os.Getenv("CF_API_KEY"), "API key (CF_API_KEY)")
+	email := flag.String("email", os.Getenv("CF_API_EMAIL"), "account email (CF_API_EMAIL)")
+	debug := flag.Bool("debug", false, "enable debug output")
+	dryRun := flag.Bool("dry-run", false, "describe mutating requests instead of sending them")
+	auditPath := flag.String("audit", os.Getenv("CF_AUDIT_LOG"), "append mutating requests to this JSON lines file (CF_AUDIT_LOG)")
+	journal := flag.String("journal", os.Getenv("CF_JOURNAL"), "record edited and deleted records in this undo journal (CF_JOURNAL)")
+	operator := flag.String("operator", os.Getenv("USER"), "operator name recorded in the audit log")
+	output := flag.String("o", cloudflare.FORMAT_TABLE, "output format: table, json, yaml, csv or template=<go template>")
+	columns := flag.String("columns", "", "comma separated fields to output, by json name")
+	flag.Usage = usage
+	flag.Parse()
+
+	name, cmd, args, err := lookup(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cfctl:", err)
+		usage()
+		os.Exit(2)
+	}
+	if *key == "" || *email == "" {
+		fmt.Fprintln(os.Stderr, "cfctl: -key and -email (or CF_API_KEY and CF_API_EMAIL) are required")
+		os.Exit(2)
+	}
+
+	cf := cloudflare.Connect(*key, *email, *debug)
+	cf.DryRun = *dryRun
+	cf.Operator = *operator
+	if *journal != "" {
+		cf.Journal = cloudflare.NewUndoJournal(*journal)
+	}
+	if *auditPath != "" {
+		audit, err := cloudflare.OpenAuditLog(*auditPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cfctl:", err)
+			os.Exit(1)
+		}
+		cf.Audit = audit
+	}
+
+	result, err := cmd.run(cf, args)
+	if result != nil && err == nil {
+		if err := cloudflare.Render(os.Stdout, *output, splitColumns(*columns), result); err != nil {
+			fmt.Fprintln(os.Stderr, "cfctl:", err)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cfctl %s: %s\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// lookup finds the longest command name matching the leading arguments and
+// checks that enough arguments follow it.
+func lookup(args []string) (string, command, []string, error) {
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		cmd, ok := commands[name]
+		if !ok {
+			continue
+		}
+		rest := args[n:]
+		if len(rest) < requiredArgs(cmd.usage) {
+			return name, cmd, nil, fmt.Errorf("usage: cfctl %s %s", name, cmd.usage)
+		}
+		return name, cmd, rest, nil
+	}
+	if len(args) == 0 {
+		return "", command{}, nil, errors.New("missing command")
+	}
+	return "", command{}, nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+var usageWord = regexp.MustCompile(`<[^>]*>(\.\.\.)?|\[[^\]]*\]|\S+`)
+
+// requiredArgs counts the mandatory placeholders of a usage line: optional
+// "[...]" and repeated "..." arguments are not counted.
+func requiredArgs(usage string) int {
+	n := 0
+	for _, word := range usageWord.FindAllString(usage, -1) {
+		if !strings.HasPrefix(word, "[") && !strings.HasSuffix(word, "...") {
+			n++
+		}
+	}
+	return n
+}
+
+func keyValues(args []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", arg)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+func splitColumns(s string) []string {
+	var columns []string
+	for _, column := range strings.Split(s, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cfctl [flags] <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, strings.TrimRight("  "+name+" "+commands[name].usage, " "))
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "flags:")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,207 @@
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/* Public address sources */
+
+type AddressSource interface {
+	Address() (net.IP, error)
+}
+
+type AddressSourceFunc func() (net.IP, error)
+
+func (this AddressSourceFunc) Address() (net.IP, error) {
+	return this()
+}
+
+// InterfaceSource reads the first global unicast address of a local network
+// interface.
+type InterfaceSource struct {
+	Name string
+	Ipv6 bool
+}
+
+func (this InterfaceSource) Address() (net.IP, error) {
+	iface, err := net.InterfaceByName(this.Name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if (ipnet.IP.To4() == nil) == this.Ipv6 {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no global address on interface %s", this.Name)
+}
+
+// HttpSource asks an echo service which answers with the caller's address
+// as plain text.
+type HttpSource struct {
+	Url    string
+	Client *http.Client
+}
+
+func (this HttpSource) Address() (net.IP, error) {
+	client := this.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	response, err := client.Get(this.Url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", this.Url, response.Status)
+	}
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseAddress(string(content))
+}
+
+// CommandSource runs a command, such as a router CLI, and takes the first
+// address found in its output.
+type CommandSource struct {
+	Command string
+	Args    []string
+}
+
+func (this CommandSource) Address() (net.IP, error) {
+	output, err := exec.Command(this.Command, this.Args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseAddress(string(output))
+}
+
+func parseAddress(s string) (net.IP, error) {
+	for _, field := range strings.Fields(s) {
+		if ip := net.ParseIP(field); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("no address found in %q", strings.TrimSpace(s))
+}
+
+/* Dynamic DNS updater */
+
+type DynamicDns struct {
+	Client   RecordsAPI
+	Domain   string
+	Name     string
+	Ipv4     AddressSource
+	Ipv6     AddressSource
+	Interval time.Duration
+	Logger   *log.Logger
+}
+
+// Update points the A and AAAA records of Name at the current addresses.
+// Records are only edited when the address changed; TTL and proxy status
+// are kept.
+func (this *DynamicDns) Update() ([]Record, error) {
+	if this.Ipv4 == nil && this.Ipv6 == nil {
+		return nil, errors.New("dynamic dns: no address source")
+	}
+
+	records, err := this.Client.GetDnsRecords(this.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []Record
+	for _, family := range []struct {
+		rtype  string
+		source AddressSource
+	}{{"A", this.Ipv4}, {"AAAA", this.Ipv6}} {
+		if family.source == nil {
+			continue
+		}
+
+		ip, err := family.source.Address()
+		if err != nil {
+			return updated, fmt.Errorf("%s address: %s", family.rtype, err)
+		}
+		if (family.rtype == "A") != (ip.To4() != nil) {
+			return updated, fmt.Errorf("%s source returned %s", family.rtype, ip)
+		}
+
+		record, found := findRecord(records.Response.Objs, this.Name, family.rtype)
+		if !found {
+			return updated, fmt.Errorf("no %s record for %s", family.rtype, this.Name)
+		}
+		if net.ParseIP(record.Content).Equal(ip) {
+			continue
+		}
+
+		input := record.Input()
+		input.Content = ip.String()
+		response, err := this.Client.EditDnsRecord(this.Domain, record.Id, input.Values())
+		if err != nil {
+			return updated, err
+		}
+		this.logf("%s %s: %s -> %s", this.Name, family.rtype, record.Content, input.Content)
+		updated = append(updated, response.Response.Rec)
+	}
+	return updated, nil
+}
+
+// Run calls Update every Interval until stop is closed. Failures are logged
+// and retried on the next tick.
+func (this *DynamicDns) Run(stop <-chan struct{}) {
+	interval := this.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := this.Update(); err != nil {
+			this.logf("%s: %s", this.Name, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *DynamicDns) logf(format string, args ...interface{}) {
+	if this.Logger != nil {
+		this.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func findRecord(records []Record, name, rtype string) (Record, bool) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, record := range records {
+		if strings.ToLower(record.Name) == name && record.Type == rtype {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
@@ -0,0 +1,98 @@
+package cloudflare
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// countingRecords counts the record edits sent to Memory.
+type countingRecords struct {
+	*Memory
+	edits int
+}
+
+func (this *countingRecords) EditDnsRecord(domain, id string, values map[string]string) (RootEditRecord, error) {
+	this.edits++
+	return this.Memory.EditDnsRecord(domain, id, values)
+}
+
+func TestDynamicDnsUpdate(t *testing.T) {
+	memory := newTestMemory()
+	if _, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "home", "content": "192.0.2.1", "ttl": "120", "service_mode": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.NewDnsRecord("example.com", map[string]string{"type": "AAAA", "name": "home", "content": "2001:db8::1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	address := "192.0.2.1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(address + "\n"))
+	}))
+	defer server.Close()
+
+	client := &countingRecords{Memory: memory}
+	ddns := &DynamicDns{
+		Client: client,
+		Domain: "example.com",
+		Name:   "home.example.com",
+		Ipv4:   HttpSource{Url: server.URL},
+		Ipv6: AddressSourceFunc(func() (net.IP, error) {
+			return net.ParseIP("2001:db8:0:0::1"), nil
+		}),
+		Logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	updated, err := ddns.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) > 0 || client.edits > 0 {
+		t.Errorf("unchanged addresses updated %+v with %d edits", updated, client.edits)
+	}
+
+	address = "198.51.100.7"
+	updated, err = ddns.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || client.edits != 1 {
+		t.Fatalf("updated %+v with %d edits, want the A record only", updated, client.edits)
+	}
+	record := updated[0]
+	if record.Type != "A" || record.Content != address || record.Ttl != "120" || record.ServiceMode != "1" {
+		t.Errorf("record %+v, want %s with ttl and proxy status kept", record, address)
+	}
+
+	if _, err := ddns.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if client.edits != 1 {
+		t.Errorf("%d edits after an unchanged address, want 1", client.edits)
+	}
+}
+
+func TestDynamicDnsUpdateErrors(t *testing.T) {
+	memory := newTestMemory()
+	ipv6 := AddressSourceFunc(func() (net.IP, error) {
+		return net.ParseIP("2001:db8::1"), nil
+	})
+
+	tests := []struct {
+		ddns DynamicDns
+		err  string
+	}{
+		{DynamicDns{Client: memory, Domain: "example.com", Name: "home.example.com"}, "dynamic dns: no address source"},
+		{DynamicDns{Client: memory, Domain: "example.com", Name: "home.example.com", Ipv4: ipv6}, "A source returned 2001:db8::1"},
+		{DynamicDns{Client: memory, Domain: "example.com", Name: "home.example.com", Ipv6: ipv6}, "no AAAA record for home.example.com"},
+	}
+	for _, test := range tests {
+		if _, err := test.ddns.Update(); err == nil || err.Error() != test.err {
+			t.Errorf("error %v, want %q", err, test.err)
+		}
+	}
+}
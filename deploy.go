@@ -0,0 +1,170 @@
+package cloudflare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/* Purge of the files changed by a static site deploy */
+
+// Manifest maps the slash separated path of each file of a build
+// directory to the SHA-256 of its content.
+type Manifest map[string]string
+
+type Deploy struct {
+	Dir       string
+	Manifest  string
+	BaseUrl   string
+	CleanUrls bool
+	Purge     PurgeOptions
+}
+
+func HashDirectory(dir string) (Manifest, error) {
+	manifest := make(Manifest)
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(name)
+		if err != nil {
+			return err
+		}
+		manifest[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func hashFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// LoadManifest reads a manifest saved by SaveManifest. A missing file is
+// an empty manifest, as for a first deploy.
+func LoadManifest(name string) (Manifest, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func SaveManifest(name string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// ChangedFiles lists the files added, modified or removed between two
+// manifests, sorted.
+func ChangedFiles(previous, current Manifest) []string {
+	var changed []string
+	for name, sum := range current {
+		if previous[name] != sum {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// FileUrls returns the public URLs serving file: index.html is also served
+// as its directory with and without trailing slash, and with cleanUrls
+// page.html is also served as page.
+func FileUrls(baseUrl, file string, cleanUrls bool) []string {
+	base := strings.TrimSuffix(baseUrl, "/")
+	escape := func(p string) string {
+		return base + (&url.URL{Path: "/" + p}).EscapedPath()
+	}
+
+	urls := []string{escape(file)}
+	dir, name := path.Split(file)
+	switch {
+	case name == "index.html":
+		urls = append(urls, escape(dir))
+		if dir != "" {
+			urls = append(urls, escape(strings.TrimSuffix(dir, "/")))
+		}
+	case cleanUrls && strings.HasSuffix(name, ".html"):
+		urls = append(urls, escape(strings.TrimSuffix(file, ".html")))
+	}
+	return urls
+}
+
+// PurgeDeploy purges the URLs of the files of deploy.Dir that changed since
+// the manifest saved by the previous deploy, then saves the new manifest.
+// The manifest is left untouched when the purge fails or some URLs are
+// invalid, so that the next run purges the same files again, and in the
+// dry-run of a Cloudflare client.
+func PurgeDeploy(client CacheAPI, domain string, deploy Deploy) (PurgeReport, error) {
+	current, err := HashDirectory(deploy.Dir)
+	if err != nil {
+		return PurgeReport{}, err
+	}
+	previous, err := LoadManifest(deploy.Manifest)
+	if err != nil {
+		return PurgeReport{}, err
+	}
+
+	var urls []string
+	for _, file := range ChangedFiles(previous, current) {
+		urls = append(urls, FileUrls(deploy.BaseUrl, file, deploy.CleanUrls)...)
+	}
+
+	report, err := PurgeFiles(client, domain, urls, deploy.Purge)
+	if err != nil {
+		return report, err
+	}
+	if len(report.Invalid) > 0 {
+		return report, fmt.Errorf("%d urls are not in zone %s, manifest not saved", len(report.Invalid), domain)
+	}
+	if cf, ok := client.(*Cloudflare); ok && cf.DryRun {
+		return report, nil
+	}
+	return report, SaveManifest(deploy.Manifest, current)
+}
@@ -0,0 +1,85 @@
+package cloudflare
+
+import (
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/* Development mode scoped to a function or a time window */
+
+// Expires returns ExpiresOn as a time, zero when development mode is off.
+func (this DevMode) Expires() time.Time {
+	if this.ExpiresOn <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(this.ExpiresOn)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// WithDevMode turns development mode on for domain, calls fn with its
+// expiry and turns it off again when fn returns or panics. An interrupt or
+// SIGTERM received meanwhile turns it off before being delivered again to
+// the process. The error of fn is returned first, then the one of turning
+// development mode off.
+func (this *Cloudflare) WithDevMode(domain string, fn func(expires time.Time) error) (err error) {
+	expires, err := this.SetDevMode(domain, true)
+	if err != nil {
+		return err
+	}
+
+	var once sync.Once
+	var revertErr error
+	revert := func() error {
+		once.Do(func() {
+			_, revertErr = this.SetDevMode(domain, false)
+		})
+		return revertErr
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(done)
+	}()
+
+	go func() {
+		select {
+		case sig := <-signals:
+			if err := revert(); err != nil {
+				log.Printf("devmode: %s: %s", domain, err)
+			}
+			signal.Stop(signals)
+			process, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = process.Signal(sig)
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		if revertErr := revert(); err == nil {
+			err = revertErr
+		}
+	}()
+	return fn(expires)
+}
+
+// DevModeWindow turns development mode on for domain during window, then
+// turns it off. Cloudflare turns it off by itself after three hours.
+func (this *Cloudflare) DevModeWindow(domain string, window time.Duration) error {
+	return this.WithDevMode(domain, func(time.Time) error {
+		time.Sleep(window)
+		return nil
+	})
+}
@@ -0,0 +1,143 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Fields of Record that are not compared: the key fields, identifiers that
+// differ between zones or backups, and values Cloudflare recomputes.
+var diffIgnored = map[string]bool{
+	"Id":             true,
+	"Tag":            true,
+	"ZoneName":       true,
+	"Name":           true,
+	"DisplayName":    true,
+	"Type":           true,
+	"Content":        true,
+	"DisplayContent": true,
+	"TtlCeil":        true,
+	"SslId":          true,
+	"SslStatus":      true,
+	"SslExpiresOn":   true,
+	"Props":          true,
+}
+
+type RecordDiff struct {
+	Added    []Record       `json:"added"`
+	Removed  []Record       `json:"removed"`
+	Modified []RecordChange `json:"modified"`
+}
+
+type RecordChange struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Content string        `json:"content"`
+	Old     Record        `json:"old"`
+	New     Record        `json:"new"`
+	Fields  []FieldChange `json:"fields"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// DiffRecords compares two record sets keyed on name, type and content.
+// Records present in both with different field values are reported as
+// modified.
+func DiffRecords(from, to []Record) RecordDiff {
+	diff := RecordDiff{}
+
+	remaining := make(map[string][]Record)
+	for _, record := range to {
+		key := syncKey(record.Name, record.Type, record.Content)
+		remaining[key] = append(remaining[key], record)
+	}
+
+	for _, before := range from {
+		key := syncKey(before.Name, before.Type, before.Content)
+		matches := remaining[key]
+		if len(matches) == 0 {
+			diff.Removed = append(diff.Removed, before)
+			continue
+		}
+		after := matches[0]
+		remaining[key] = matches[1:]
+
+		if fields := diffFields(before, after); len(fields) > 0 {
+			diff.Modified = append(diff.Modified, RecordChange{
+				Name:    after.Name,
+				Type:    after.Type,
+				Content: after.Content,
+				Old:     before,
+				New:     after,
+				Fields:  fields,
+			})
+		}
+	}
+
+	for _, record := range to {
+		key := syncKey(record.Name, record.Type, record.Content)
+		if len(remaining[key]) > 0 {
+			diff.Added = append(diff.Added, remaining[key][0])
+			remaining[key] = remaining[key][1:]
+		}
+	}
+	return diff
+}
+
+func diffFields(before, after Record) []FieldChange {
+	var fields []FieldChange
+
+	a := reflect.ValueOf(before)
+	b := reflect.ValueOf(after)
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if diffIgnored[field.Name] {
+			continue
+		}
+		oldValue := fmt.Sprint(a.Field(i).Interface())
+		newValue := fmt.Sprint(b.Field(i).Interface())
+		if oldValue == newValue {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, FieldChange{Field: name, Old: oldValue, New: newValue})
+	}
+	return fields
+}
+
+func (this RecordDiff) Empty() bool {
+	return len(this.Added) == 0 && len(this.Removed) == 0 && len(this.Modified) == 0
+}
+
+func (this RecordDiff) String() string {
+	var b strings.Builder
+	for _, record := range this.Removed {
+		fmt.Fprintf(&b, "- %s %s %s\n", record.Name, record.Type, record.Content)
+	}
+	for _, record := range this.Added {
+		fmt.Fprintf(&b, "+ %s %s %s\n", record.Name, record.Type, record.Content)
+	}
+	for _, change := range this.Modified {
+		var fields []string
+		for _, field := range change.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s -> %s", field.Field, field.Old, field.New))
+		}
+		fmt.Fprintf(&b, "~ %s %s %s: %s\n", change.Name, change.Type, change.Content, strings.Join(fields, ", "))
+	}
+	return b.String()
+}
+
+func (this RecordDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(this, "", "  ")
+}
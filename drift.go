@@ -0,0 +1,196 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/* Zone settings drift detection */
+
+// SettingsBaseline is the expected configuration of a zone. Ssl and
+// WafProfile are only reported as they cannot be changed through the API.
+type SettingsBaseline struct {
+	ZoneConfig
+	Ssl        *string `json:"ssl,omitempty"`
+	WafProfile *string `json:"waf_profile,omitempty"`
+}
+
+type Drift struct {
+	Zone      string `json:"zone"`
+	Setting   string `json:"setting"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+	Corrected bool   `json:"corrected"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (this Drift) String() string {
+	s := fmt.Sprintf("%s: %s is %s, expected %s", this.Zone, this.Setting, this.Actual, this.Expected)
+	if this.Corrected {
+		s += " (corrected)"
+	}
+	if this.Error != "" {
+		s += " (" + this.Error + ")"
+	}
+	return s
+}
+
+// CompareSettings lists the settings of a zone that differ from baseline.
+func CompareSettings(zone string, baseline SettingsBaseline, settings Settings) []Drift {
+	have := settings.Typed()
+	checks := []struct {
+		name     string
+		expected *string
+		actual   string
+	}{
+		{"security_level", baseline.SecurityLevel, settings.SecLvl},
+		{"cache_level", baseline.CacheLevel, settings.CacheLevel},
+		{"dev_mode", boolString(baseline.DevMode), strconv.FormatBool(*have.DevMode)},
+		{"ipv6", boolString(baseline.Ipv6), strconv.FormatBool(*have.Ipv6)},
+		{"rocket_loader", baseline.RocketLoader, settings.Async},
+		{"minify", intString(baseline.Minify), settings.Minify},
+		{"ssl", baseline.Ssl, settings.Ssl},
+		{"waf_profile", baseline.WafProfile, settings.WafProfile},
+	}
+
+	var drifts []Drift
+	for _, check := range checks {
+		if check.expected != nil && *check.expected != check.actual {
+			drifts = append(drifts, Drift{
+				Zone:     zone,
+				Setting:  check.name,
+				Expected: *check.expected,
+				Actual:   check.actual,
+			})
+		}
+	}
+	return drifts
+}
+
+func boolString(b *bool) *string {
+	if b == nil {
+		return nil
+	}
+	return String(strconv.FormatBool(*b))
+}
+
+func intString(i *int) *string {
+	if i == nil {
+		return nil
+	}
+	return String(strconv.Itoa(*i))
+}
+
+// ParseBaselines reads baselines keyed by zone name, in JSON.
+func ParseBaselines(data []byte) (map[string]SettingsBaseline, error) {
+	baselines := make(map[string]SettingsBaseline)
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return nil, err
+	}
+	return baselines, nil
+}
+
+type DriftWatcher struct {
+	Client    SettingsAPI
+	Baselines map[string]SettingsBaseline
+	Interval  time.Duration
+	Correct   bool
+	Report    func(Drift)
+}
+
+// Check compares every zone with its baseline. With Correct, drifted
+// settings that the API can change are set back to the baseline.
+func (this *DriftWatcher) Check() ([]Drift, error) {
+	zones := make([]string, 0, len(this.Baselines))
+	for zone := range this.Baselines {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	var all []Drift
+	var failed []string
+	for _, zone := range zones {
+		drifts, err := this.checkZone(zone, this.Baselines[zone])
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", zone, err))
+			continue
+		}
+		for _, drift := range drifts {
+			this.report(drift)
+		}
+		all = append(all, drifts...)
+	}
+	if len(failed) > 0 {
+		return all, fmt.Errorf("drift check failed for %d zones: %v", len(failed), failed)
+	}
+	return all, nil
+}
+
+func (this *DriftWatcher) checkZone(zone string, baseline SettingsBaseline) ([]Drift, error) {
+	settings, err := this.Client.GetZoneSettings(zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.Response.Result) == 0 {
+		return nil, fmt.Errorf("no zone settings returned")
+	}
+
+	drifts := CompareSettings(zone, baseline, settings.Response.Result[0])
+	if !this.Correct || len(drifts) == 0 {
+		return drifts, nil
+	}
+
+	// Mirage 2 cannot be read back, so it is never reported as drifted
+	// and is not sent again on every correction.
+	config := baseline.ZoneConfig
+	config.Mirage2 = nil
+	changed, err := ApplyZoneConfig(this.Client, zone, config)
+	corrected := make(map[string]bool)
+	for _, name := range changed {
+		corrected[name] = true
+	}
+	for i := range drifts {
+		switch {
+		case corrected[drifts[i].Setting]:
+			drifts[i].Corrected = true
+		case drifts[i].Setting == "ssl" || drifts[i].Setting == "waf_profile":
+			drifts[i].Error = "cannot be changed through the API"
+		case err != nil:
+			drifts[i].Error = err.Error()
+		}
+	}
+	return drifts, nil
+}
+
+func (this *DriftWatcher) report(drift Drift) {
+	if this.Report != nil {
+		this.Report(drift)
+		return
+	}
+	log.Println(drift)
+}
+
+// Run calls Check every Interval until stop is closed.
+func (this *DriftWatcher) Run(stop <-chan struct{}) {
+	interval := this.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := this.Check(); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
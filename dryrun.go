@@ -0,0 +1,168 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DRY_RUN string = "dry_run"
+)
+
+var mutatingActions = map[string]bool{
+	"rec_new":         true,
+	"rec_edit":        true,
+	"rec_delete":      true,
+	"sec_lvl":         true,
+	"cache_lvl":       true,
+	"devmode":         true,
+	"fpurge_ts":       true,
+	"zone_file_purge": true,
+	"ban":             true,
+	"wl":              true,
+	"nul":             true,
+	"mirage2":         true,
+	"minify":          true,
+	"async":           true,
+	"ipv46":           true,
+	"zone_grab":       true,
+}
+
+func isMutating(action string) bool {
+	return mutatingActions[action]
+}
+
+// dryRun validates a mutating request and answers in place of the API with
+// result "dry_run" and a description of the request as the message. The
+// description is also logged.
+func dryRun(apiUrl string, values url.Values) ([]byte, error) {
+	if err := validateRequest(values); err != nil {
+		return nil, err
+	}
+
+	description := describeRequest(apiUrl, values)
+	log.Println("dry run:", description)
+
+	data := map[string]string{
+		"result": DRY_RUN,
+		"msg":    description,
+	}
+	return json.Marshal(data)
+}
+
+func describeRequest(apiUrl string, values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k == "tkn" || k == "email" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+strconv.Quote(values.Get(k)))
+	}
+	return "POST " + apiUrl + " " + strings.Join(parts, " ")
+}
+
+func validateRequest(values url.Values) error {
+	action := values.Get("a")
+	if values.Get("z") == "" {
+		return errors.New(action + ": missing zone")
+	}
+
+	v := values.Get("v")
+	switch action {
+	case "rec_new":
+		for _, k := range []string{"type", "name", "content"} {
+			if values.Get(k) == "" {
+				return errors.New(action + ": missing " + k)
+			}
+		}
+		return validateRecordValues(action, values)
+	case "rec_edit":
+		if values.Get("id") == "" {
+			return errors.New(action + ": missing record id")
+		}
+		return validateRecordValues(action, values)
+	case "rec_delete":
+		if values.Get("id") == "" {
+			return errors.New(action + ": missing record id")
+		}
+	case "sec_lvl":
+		return validateChoice(action, v, "help", "high", "med", "low", "eoff")
+	case "cache_lvl":
+		return validateChoice(action, v, "agg", "basic")
+	case "devmode", "mirage2":
+		return validateChoice(action, v, "0", "1")
+	case "ipv46":
+		return validateChoice(action, v, "0", "3")
+	case "async":
+		return validateChoice(action, v, "0", "a", "m")
+	case "minify":
+		return validateChoice(action, v, "0", "1", "2", "3", "4", "5", "6", "7")
+	case "fpurge_ts":
+		return validateChoice(action, v, "1")
+	case "zone_file_purge":
+		u, err := url.Parse(values.Get("url"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(action + ": invalid url " + strconv.Quote(values.Get("url")))
+		}
+	case "ban", "wl", "nul":
+		key := values.Get("key")
+		if net.ParseIP(key) == nil {
+			if _, _, err := net.ParseCIDR(key); err != nil {
+				return errors.New(action + ": invalid ip " + strconv.Quote(key))
+			}
+		}
+	case "zone_grab":
+		if values.Get("zid") == "" {
+			return errors.New(action + ": missing zone id")
+		}
+	}
+	return nil
+}
+
+func validateRecordValues(action string, values url.Values) error {
+	rtype := values.Get("type")
+	content := values.Get("content")
+	switch rtype {
+	case "":
+	case "A", "AAAA":
+		ip := net.ParseIP(content)
+		if content != "" && (ip == nil || (rtype == "A") != (ip.To4() != nil)) {
+			return errors.New(action + ": invalid " + rtype + " address " + strconv.Quote(content))
+		}
+	case "CNAME", "MX", "TXT", "SPF", "NS", "SRV", "LOC":
+	default:
+		return errors.New(action + ": unsupported record type " + rtype)
+	}
+
+	if ttl := values.Get("ttl"); ttl != "" {
+		n, err := strconv.Atoi(ttl)
+		if err != nil || (n != 1 && (n < 120 || n > 86400)) {
+			return errors.New(action + ": ttl must be 1 or between 120 and 86400")
+		}
+	}
+	if mode := values.Get("service_mode"); mode != "" {
+		return validateChoice(action, mode, "0", "1")
+	}
+	return nil
+}
+
+func validateChoice(action, value string, choices ...string) error {
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+	return errors.New(action + ": invalid value " + strconv.Quote(value) + ", expected one of " + strings.Join(choices, ", "))
+}
@@ -0,0 +1,349 @@
+package cloudflare
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+const (
+	FORMAT_TABLE    string = "table"
+	FORMAT_JSON     string = "json"
+	FORMAT_YAML     string = "yaml"
+	FORMAT_CSV      string = "csv"
+	FORMAT_TEMPLATE string = "template="
+)
+
+/*
+Rendering of API results for scripts. A value is either a struct or a slice
+of structs; each struct is a row and its json field names are the columns.
+*/
+
+type orderedField struct {
+	Key   string
+	Value interface{}
+}
+
+type orderedMap []orderedField
+
+func (this orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range this {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Render writes v to w in format: "table", "json", "yaml", "csv" or
+// "template=<text/template>" which is executed once per row. Columns, when
+// given, select and order the fields by json name.
+func Render(w io.Writer, format string, columns []string, v interface{}) error {
+	if strings.HasPrefix(format, FORMAT_TEMPLATE) {
+		return renderTemplate(w, strings.TrimPrefix(format, FORMAT_TEMPLATE), v)
+	}
+
+	items, single := formatItems(v)
+	if len(columns) == 0 {
+		switch format {
+		case FORMAT_JSON:
+			return renderJSON(w, v)
+		case FORMAT_YAML:
+			return yamlEncode(w, formatGeneric(reflect.ValueOf(v)))
+		}
+		columns = formatColumns(items)
+	}
+
+	rows := make([]orderedMap, 0, len(items))
+	for _, item := range items {
+		row, err := formatRow(item, columns)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	switch format {
+	case FORMAT_TABLE, "":
+		return renderTable(w, columns, rows)
+	case FORMAT_CSV:
+		return renderCsv(w, columns, rows)
+	case FORMAT_JSON:
+		if single && len(rows) == 1 {
+			return renderJSON(w, rows[0])
+		}
+		return renderJSON(w, rows)
+	case FORMAT_YAML:
+		if single && len(rows) == 1 {
+			return yamlEncode(w, rows[0])
+		}
+		generic := make([]interface{}, len(rows))
+		for i, row := range rows {
+			generic[i] = row
+		}
+		return yamlEncode(w, generic)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// formatItems flattens v into the structs to render. Nil values and nil
+// elements are left out. single reports whether v was one value rather
+// than a list.
+func formatItems(v interface{}) ([]reflect.Value, bool) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil, true
+	}
+
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return []reflect.Value{value}, true
+	}
+	items := make([]reflect.Value, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		item := value.Index(i)
+		for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
+		if item.IsValid() {
+			items = append(items, item)
+		}
+	}
+	return items, false
+}
+
+func formatColumns(items []reflect.Value) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	item := items[0]
+	switch item.Kind() {
+	case reflect.Struct:
+		var columns []string
+		for _, field := range structFields(item.Type()) {
+			columns = append(columns, field.name)
+		}
+		return columns
+	case reflect.Map:
+		var columns []string
+		for _, key := range item.MapKeys() {
+			columns = append(columns, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(columns)
+		return columns
+	}
+	return []string{"value"}
+}
+
+func formatRow(item reflect.Value, columns []string) (orderedMap, error) {
+	row := make(orderedMap, 0, len(columns))
+	for _, column := range columns {
+		value, ok := formatLookup(item, column)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		row = append(row, orderedField{Key: column, Value: value})
+	}
+	return row, nil
+}
+
+func formatLookup(item reflect.Value, column string) (interface{}, bool) {
+	switch item.Kind() {
+	case reflect.Struct:
+		for _, field := range structFields(item.Type()) {
+			if field.name == column {
+				return formatGeneric(item.Field(field.index)), true
+			}
+		}
+		return nil, false
+	case reflect.Map:
+		value := item.MapIndex(reflect.ValueOf(column))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return formatGeneric(value), true
+	}
+	if column == "value" {
+		return formatGeneric(item), true
+	}
+	return nil, false
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+// structFields lists the exported fields of t under their json names.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+	return fields
+}
+
+// formatGeneric converts a value to ordered maps, slices and scalars so that
+// every format sees fields in declaration order.
+func formatGeneric(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	if value.CanInterface() {
+		if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+			if text, err := marshaler.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return formatGeneric(value.Elem())
+	case reflect.Struct:
+		fields := structFields(value.Type())
+		m := make(orderedMap, 0, len(fields))
+		for _, field := range fields {
+			m = append(m, orderedField{Key: field.name, Value: formatGeneric(value.Field(field.index))})
+		}
+		return m
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		m := make(orderedMap, 0, len(keys))
+		for _, key := range keys {
+			m = append(m, orderedField{Key: fmt.Sprint(key.Interface()), Value: formatGeneric(value.MapIndex(key))})
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return []interface{}{}
+		}
+		list := make([]interface{}, value.Len())
+		for i := range list {
+			list[i] = formatGeneric(value.Index(i))
+		}
+		return list
+	}
+	return value.Interface()
+}
+
+// formatCell renders a value on a single line for tables and CSV.
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case orderedMap, []interface{}:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(content)
+	}
+	return fmt.Sprint(value)
+}
+
+func renderJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func renderTable(w io.Writer, columns []string, rows []orderedMap) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, field := range row {
+			cells[i] = strings.Replace(formatCell(field.Value), "\t", " ", -1)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func renderCsv(w io.Writer, columns []string, rows []orderedMap) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, field := range row {
+			cells[i] = formatCell(field.Value)
+		}
+		if err := writer.Write(cells); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func renderTemplate(w io.Writer, text string, v interface{}) error {
+	if text == "" {
+		return errors.New("empty output template")
+	}
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	items, _ := formatItems(v)
+	for _, item := range items {
+		if err := tmpl.Execute(w, item.Interface()); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cloudflare
+
+import (
+	"strings"
+	"testing"
+)
+
+type formatTestRow struct {
+	Name    string   `json:"name"`
+	Ttl     int      `json:"ttl"`
+	Tags    []string `json:"tags"`
+	Proxied *bool    `json:"proxied,omitempty"`
+	Secret  string   `json:"-"`
+}
+
+var formatTestRows = []*formatTestRow{
+	{Name: "www.example.com", Ttl: 300, Tags: []string{"web"}, Proxied: Bool(true), Secret: "x"},
+	nil,
+	{Name: "mail, \"primary\"", Ttl: 1},
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format  string
+		columns []string
+		v       interface{}
+		want    string
+	}{
+		{FORMAT_TABLE, nil, formatTestRows, "NAME             TTL  TAGS     PROXIED\n" +
+			"www.example.com  300  [\"web\"]  true\n" +
+			"mail, \"primary\"  1    []       \n"},
+		{FORMAT_TABLE, []string{"ttl", "name"}, formatTestRows[0], `TTL  NAME
+300  www.example.com
+`},
+		{FORMAT_CSV, nil, formatTestRows, `name,ttl,tags,proxied
+www.example.com,300,"[""web""]",true
+"mail, ""primary""",1,[],
+`},
+		{FORMAT_CSV, []string{"name"}, []formatTestRow{}, "name\n"},
+		{FORMAT_JSON, []string{"name", "ttl"}, formatTestRows, `[
+  {
+    "name": "www.example.com",
+    "ttl": 300
+  },
+  {
+    "name": "mail, \"primary\"",
+    "ttl": 1
+  }
+]
+`},
+		{FORMAT_JSON, []string{"ttl"}, formatTestRows[0], "{\n  \"ttl\": 300\n}\n"},
+		{FORMAT_YAML, nil, formatTestRows[0], `name: www.example.com
+ttl: 300
+tags:
+- web
+proxied: true
+`},
+		{FORMAT_YAML, []string{"name", "tags"}, []formatTestRow{{Name: "true", Tags: []string{"12345", "a: b", ""}}}, `- name: "true"
+  tags:
+  - "12345"
+  - "a: b"
+  - ""
+`},
+		{FORMAT_TEMPLATE + "{{.Name}} {{.Ttl}}", nil, formatTestRows, "www.example.com 300\nmail, \"primary\" 1\n"},
+		{FORMAT_TEMPLATE + "{{.Name}}", nil, nil, ""},
+		{FORMAT_TEMPLATE + "{{.Name}}", nil, []*Record{nil}, ""},
+		{FORMAT_TABLE, nil, (*Record)(nil), "\n"},
+		{FORMAT_CSV, nil, nil, "\n"},
+	}
+
+	for _, test := range tests {
+		var b strings.Builder
+		if err := Render(&b, test.format, test.columns, test.v); err != nil {
+			t.Errorf("%s %v: %s", test.format, test.columns, err)
+			continue
+		}
+		if b.String() != test.want {
+			t.Errorf("%s %v:\n got %q\nwant %q", test.format, test.columns, b.String(), test.want)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		format  string
+		columns []string
+		err     string
+	}{
+		{"xml", nil, `unknown output format "xml"`},
+		{FORMAT_TABLE, []string{"name", "secret"}, `unknown column "secret"`},
+		{FORMAT_TEMPLATE, nil, "empty output template"},
+		{FORMAT_TEMPLATE + "{{.Missing}}", nil, "can't evaluate field Missing"},
+	}
+	for _, test := range tests {
+		err := Render(&strings.Builder{}, test.format, test.columns, formatTestRows)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s %v: error %v, want %q", test.format, test.columns, err, test.err)
+		}
+	}
+}
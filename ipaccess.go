@@ -0,0 +1,237 @@
+package cloudflare
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	IP_BAN    string = "ban"
+	IP_ALLOW  string = "wl"
+	IP_FORGET string = "nul"
+)
+
+/* Bulk IP access management */
+
+// IpAccessOptions deduplicate entries against the current state: Current
+// lists the known rules, and with Lookup the entries Current does not skip
+// are checked with LookupIp before being applied.
+type IpAccessOptions struct {
+	Workers  int
+	Current  []AccessRule
+	Lookup   bool
+	Progress func(done, total int)
+}
+
+type IpAccessReport struct {
+	Applied []string
+	Skipped []string
+	Failed  []IpFailure
+}
+
+type IpFailure struct {
+	Ip  string
+	Err error
+}
+
+// NormalizeIp validates an address or CIDR range and returns its canonical
+// form. Ranges are reduced to their network address and single-host ranges
+// to a plain address.
+func NormalizeIp(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return v4.String(), nil
+		}
+		return ip.String(), nil
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid ip or range %q", s)
+	}
+	ones, bits := network.Mask.Size()
+	if ones == bits {
+		return network.IP.String(), nil
+	}
+	return network.String(), nil
+}
+
+// ParseIpList reads addresses and CIDR ranges separated by whitespace or
+// commas, one or more per line, with "#" comments. Entries are normalized
+// and duplicates dropped. All invalid entries are reported together.
+func ParseIpList(r io.Reader) ([]string, error) {
+	var ips []string
+	var invalid []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.FieldsFunc(line, func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		}) {
+			ip, err := NormalizeIp(field)
+			if err != nil {
+				invalid = append(invalid, fmt.Sprintf("line %d: %s", number, err))
+				continue
+			}
+			if !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(invalid) > 0 {
+		return ips, errors.New(strings.Join(invalid, "; "))
+	}
+	return ips, nil
+}
+
+// ApplyIpAccess bans (IP_BAN), allows (IP_ALLOW) or forgets (IP_FORGET)
+// every entry of ips in parallel. Entries that already have a rule of the
+// same action are skipped; when forgetting, entries without a rule are
+// skipped. The error is non-nil when any entry failed.
+func ApplyIpAccess(client FirewallAPI, domain, action string, ips []string, options IpAccessOptions) (IpAccessReport, error) {
+	var apply func(domain, ip string) (RootModIp, error)
+	switch action {
+	case IP_BAN:
+		apply = client.DenyIP
+	case IP_ALLOW:
+		apply = client.AllowIP
+	case IP_FORGET:
+		apply = client.ForgetIP
+	default:
+		return IpAccessReport{}, fmt.Errorf("unknown ip access action %q", action)
+	}
+
+	report := IpAccessReport{}
+	var pending []string
+	seen := make(map[string]bool)
+	for _, entry := range ips {
+		ip, err := NormalizeIp(entry)
+		if err != nil {
+			report.Failed = append(report.Failed, IpFailure{Ip: entry, Err: err})
+			continue
+		}
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+
+		if options.Current != nil && accessRuleCovers(options.Current, ip, action) == (action != IP_FORGET) {
+			report.Skipped = append(report.Skipped, ip)
+			continue
+		}
+		pending = append(pending, ip)
+	}
+
+	var mu sync.Mutex
+	done := 0
+	runParallel(len(pending), options.Workers, false, func(i int) error {
+		skip := false
+		var err error
+		if options.Lookup {
+			skip, err = accessRuleSet(client, domain, pending[i], action)
+		}
+		if err == nil && !skip {
+			_, err = apply(domain, pending[i])
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			report.Failed = append(report.Failed, IpFailure{Ip: pending[i], Err: err})
+		case skip:
+			report.Skipped = append(report.Skipped, pending[i])
+		default:
+			report.Applied = append(report.Applied, pending[i])
+		}
+		done++
+		if options.Progress != nil {
+			options.Progress(done, len(pending))
+		}
+		return err
+	})
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d of %d ip entries failed", len(report.Failed), len(ips))
+	}
+	return report, nil
+}
+
+// accessRuleSet looks up the rule of ip and reports whether applying action
+// would leave it unchanged.
+func accessRuleSet(client FirewallAPI, domain, ip, action string) (bool, error) {
+	result, err := client.LookupIp(domain, ip)
+	if err != nil {
+		return false, fmt.Errorf("lookup: %s", err)
+	}
+	current := strings.ToLower(result.Response.Ip)
+	if current == "" {
+		current = IP_FORGET
+	}
+	return current == action, nil
+}
+
+// accessRuleCovers reports whether ip is equal to or inside a rule. For
+// IP_FORGET only a rule on ip itself counts, with any action: forgetting
+// an address inside a range would leave the range in place.
+func accessRuleCovers(rules []AccessRule, ip, action string) bool {
+	address := net.ParseIP(ip)
+	for _, rule := range rules {
+		if action != IP_FORGET && rule.Action != action {
+			continue
+		}
+		normalized, err := NormalizeIp(rule.Ip)
+		if err != nil {
+			continue
+		}
+		if normalized == ip {
+			return true
+		}
+		if action == IP_FORGET {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(normalized); err == nil && address != nil && network.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON reads the rule from an ip_lkup response, which is keyed by
+// the address looked up.
+func (this *LookupIp) UnmarshalJSON(data []byte) error {
+	rules := make(map[string]string)
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		this.Ip = rule
+	}
+	return nil
+}
+
+func (this IpAccessReport) String() string {
+	s := fmt.Sprintf("%d applied, %d skipped, %d failed\n", len(this.Applied), len(this.Skipped), len(this.Failed))
+	for _, failure := range this.Failed {
+		s += fmt.Sprintf("  %s: %s\n", failure.Ip, failure.Err)
+	}
+	return s
+}
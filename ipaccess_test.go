@@ -0,0 +1,124 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeIp(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{" 192.0.2.4 ", "192.0.2.4"},
+		{"::ffff:192.0.2.4", "192.0.2.4"},
+		{"2001:DB8:0::1", "2001:db8::1"},
+		{"192.0.2.77/24", "192.0.2.0/24"},
+		{"192.0.2.4/32", "192.0.2.4"},
+		{"2001:db8::1/48", "2001:db8::/48"},
+	}
+	for _, test := range tests {
+		if got, err := NormalizeIp(test.input); err != nil || got != test.want {
+			t.Errorf("%q: %q %v, want %q", test.input, got, err, test.want)
+		}
+	}
+	for _, input := range []string{"", "192.0.2", "192.0.2.4/33", "example.com"} {
+		if _, err := NormalizeIp(input); err == nil {
+			t.Errorf("%q accepted", input)
+		}
+	}
+}
+
+func TestParseIpList(t *testing.T) {
+	ips, err := ParseIpList(strings.NewReader(`# blocked ranges
+192.0.2.4, 192.0.2.0/24	2001:db8::1
+192.0.2.4/32 # same address
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"192.0.2.4", "192.0.2.0/24", "2001:db8::1"}; !reflect.DeepEqual(ips, want) {
+		t.Errorf("got %v, want %v", ips, want)
+	}
+
+	ips, err = ParseIpList(strings.NewReader("192.0.2.1 bad\n\n192.0.2.300\n"))
+	want := `line 1: invalid ip or range "bad"; line 3: invalid ip or range "192.0.2.300"`
+	if err == nil || err.Error() != want || len(ips) != 1 {
+		t.Errorf("got %v, %v, want %q", ips, err, want)
+	}
+}
+
+func TestAccessRuleCovers(t *testing.T) {
+	rules := []AccessRule{
+		{Ip: "192.0.2.0/24", Action: IP_BAN},
+		{Ip: "192.0.2.4", Action: IP_BAN},
+		{Ip: "2001:db8::/32", Action: IP_ALLOW},
+	}
+	tests := []struct {
+		ip, action string
+		want       bool
+	}{
+		{"192.0.2.9", IP_BAN, true},
+		{"192.0.2.0/24", IP_BAN, true},
+		{"192.0.2.9", IP_ALLOW, false},
+		{"198.51.100.1", IP_BAN, false},
+		{"2001:db8::1", IP_ALLOW, true},
+		{"192.0.2.4", IP_FORGET, true},
+		{"192.0.2.9", IP_FORGET, false},
+		{"2001:db8::/32", IP_FORGET, true},
+	}
+	for _, test := range tests {
+		if got := accessRuleCovers(rules, test.ip, test.action); got != test.want {
+			t.Errorf("%s %s: %v, want %v", test.action, test.ip, got, test.want)
+		}
+	}
+}
+
+func TestApplyIpAccessOnMemory(t *testing.T) {
+	memory := newTestMemory()
+	memory.DenyIP("example.com", "192.0.2.4")
+
+	progress := 0
+	report, err := ApplyIpAccess(memory, "example.com", IP_BAN, []string{"192.0.2.4", "192.0.2.5", "198.51.100.1", "198.51.100.1/32", "bad"}, IpAccessOptions{
+		Workers:  2,
+		Current:  []AccessRule{{Ip: "198.51.100.0/24", Action: IP_BAN}},
+		Lookup:   true,
+		Progress: func(done, total int) { progress = done },
+	})
+	if err == nil || err.Error() != "1 of 5 ip entries failed" {
+		t.Errorf("error %v", err)
+	}
+	sort.Strings(report.Skipped)
+	if !reflect.DeepEqual(report.Applied, []string{"192.0.2.5"}) || !reflect.DeepEqual(report.Skipped, []string{"192.0.2.4", "198.51.100.1"}) ||
+		len(report.Failed) != 1 || report.Failed[0].Ip != "bad" || progress != 2 {
+		t.Errorf("report %+v, progress %d", report, progress)
+	}
+
+	report, err = ApplyIpAccess(memory, "example.com", IP_FORGET, []string{"192.0.2.4", "192.0.2.6"}, IpAccessOptions{Lookup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Applied, []string{"192.0.2.4"}) || !reflect.DeepEqual(report.Skipped, []string{"192.0.2.6"}) {
+		t.Errorf("report %+v", report)
+	}
+	access, _ := memory.Access("example.com")
+	if want := []AccessRule{{Ip: "192.0.2.5", Action: IP_BAN}}; !reflect.DeepEqual(access, want) {
+		t.Errorf("access %v, want %v", access, want)
+	}
+
+	if _, err := ApplyIpAccess(memory, "example.org", IP_BAN, []string{"192.0.2.7"}, IpAccessOptions{Lookup: true}); err == nil {
+		t.Error("lookup failure not reported")
+	}
+}
+
+func TestLookupIpResponse(t *testing.T) {
+	data := RootLookupIp{}
+	if err := json.Unmarshal([]byte(`{"response":{"192.0.2.4":"BAN"},"result":"success","msg":null}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Response.Ip != "BAN" {
+		t.Errorf("rule %q, want BAN", data.Response.Ip)
+	}
+}
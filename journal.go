@@ -0,0 +1,218 @@
+package cloudflare
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	JOURNAL_EDIT   string = "edit"
+	JOURNAL_DELETE string = "delete"
+)
+
+/* Undo journal of record edits and deletions */
+
+type JournalEntry struct {
+	Time   time.Time `json:"time"`
+	Zone   string    `json:"zone"`
+	Action string    `json:"action"`
+	Record Record    `json:"record"`
+}
+
+// UndoJournal keeps the previous state of edited and deleted records in a
+// JSON lines file, oldest first.
+type UndoJournal struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewUndoJournal(path string) *UndoJournal {
+	return &UndoJournal{Path: path}
+}
+
+func (this *UndoJournal) Append(entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	file, err := os.OpenFile(this.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (this *UndoJournal) Entries() ([]JournalEntry, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.read()
+}
+
+func (this *UndoJournal) read() ([]JournalEntry, error) {
+	file, err := os.Open(this.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s: %s", this.Path, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// drop removes the last entry of the journal.
+func (this *UndoJournal) drop() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	entries, err := this.read()
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	tmp := this.Path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries[:len(entries)-1] {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, this.Path)
+}
+
+// journalEntry captures the current state of record id before it is
+// edited or deleted. It is nil without a journal or in dry-run mode.
+func (this *Cloudflare) journalEntry(domain, id, action string) (*JournalEntry, error) {
+	if this.Journal == nil || this.DryRun {
+		return nil, nil
+	}
+
+	// The journal needs the record as it is now, not a cached copy.
+	this.InvalidateZone(domain)
+	records, err := this.GetDnsRecords(domain)
+	if err != nil {
+		return nil, fmt.Errorf("undo journal: %s", err)
+	}
+	for _, record := range records.Response.Objs {
+		if record.Id == id {
+			return &JournalEntry{
+				Zone:   domain,
+				Action: action,
+				Record: record,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("undo journal: record %s not found in %s", id, domain)
+}
+
+// journal appends entry once its change went through. Failing to write is
+// logged but does not fail the change, which has already been made.
+func (this *Cloudflare) journal(entry *JournalEntry) {
+	if entry == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	if err := this.Journal.Append(*entry); err != nil {
+		log.Println("undo journal:", err)
+	}
+}
+
+// Undo reverts the last n journaled changes, newest first: deleted records
+// are created again and edited records get their previous values back.
+// Each reverted change is removed from the journal; on failure the
+// remaining ones are kept.
+func (this *Cloudflare) Undo(n int) ([]JournalEntry, error) {
+	if this.Journal == nil {
+		return nil, fmt.Errorf("undo: no journal configured")
+	}
+	return this.Journal.Undo(this, n)
+}
+
+// Undo reverts the last n entries of the journal through client, see
+// Cloudflare.Undo.
+func (this *UndoJournal) Undo(client RecordsAPI, n int) ([]JournalEntry, error) {
+	// A Cloudflare client would journal the edits that undo, and keeps
+	// the journal as it is in dry-run.
+	edit := client.EditDnsRecord
+	dryRun := false
+	if cf, ok := client.(*Cloudflare); ok {
+		edit = cf.editDnsRecord
+		dryRun = cf.DryRun
+	}
+
+	entries, err := this.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	// Records created again get a new id, which older entries must use.
+	ids := make(map[string]string)
+
+	var undone []JournalEntry
+	for i := len(entries) - 1; i >= len(entries)-n; i-- {
+		entry := entries[i]
+		id := entry.Record.Id
+		if replaced, ok := ids[id]; ok {
+			id = replaced
+		}
+
+		values := entry.Record.Input().Values()
+		switch entry.Action {
+		case JOURNAL_DELETE:
+			response, err := client.NewDnsRecord(entry.Zone, values)
+			if err != nil {
+				return undone, fmt.Errorf("undo delete of %s %s: %s", entry.Record.Name, entry.Record.Type, err)
+			}
+			ids[entry.Record.Id] = response.Response.Rec.Id
+		case JOURNAL_EDIT:
+			if _, err := edit(entry.Zone, id, values); err != nil {
+				return undone, fmt.Errorf("undo edit of %s %s: %s", entry.Record.Name, entry.Record.Type, err)
+			}
+		default:
+			return undone, fmt.Errorf("undo: unknown action %q", entry.Action)
+		}
+
+		if !dryRun {
+			if err := this.drop(); err != nil {
+				return undone, err
+			}
+		}
+		undone = append(undone, entry)
+	}
+	return undone, nil
+}
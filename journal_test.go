@@ -0,0 +1,94 @@
+package cloudflare
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUndoOnMemory(t *testing.T) {
+	memory := newTestMemory()
+	created, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www", "content": "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := created.Response.Rec
+	deleted, err := memory.NewDnsRecord("example.com", map[string]string{"type": "TXT", "name": "@", "content": "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := memoryRecords(t, memory, "example.com")
+
+	journal := NewUndoJournal(filepath.Join(t.TempDir(), "journal"))
+	journal.Append(JournalEntry{Time: time.Now(), Zone: "example.com", Action: JOURNAL_EDIT, Record: before})
+	memory.EditDnsRecord("example.com", before.Id, map[string]string{"content": "192.0.2.2"})
+	journal.Append(JournalEntry{Time: time.Now(), Zone: "example.com", Action: JOURNAL_DELETE, Record: deleted.Response.Rec})
+	memory.DeleteDnsRecord("example.com", deleted.Response.Rec.Id)
+
+	undone, err := journal.Undo(memory, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 2 || undone[0].Action != JOURNAL_DELETE {
+		t.Errorf("undone %+v, want the deletion then the edit", undone)
+	}
+	if got := memoryRecords(t, memory, "example.com"); !reflect.DeepEqual(got, records) {
+		t.Errorf("records\n got %+v\nwant %+v", got, records)
+	}
+	if entries, _ := journal.Entries(); len(entries) > 0 {
+		t.Errorf("journal not emptied: %+v", entries)
+	}
+}
+
+func TestJournalOnlyKeepsSentChanges(t *testing.T) {
+	cf, server := newTestClient(t, func(values url.Values) string {
+		switch values.Get("a") {
+		case "rec_load_all":
+			return `{"result":"success","response":{"objs":[
+				{"rec_id":"1","name":"www.example.com","type":"A","content":"192.0.2.1","ttl":"1","service_mode":"0"}
+			]}}`
+		case "rec_delete":
+			return `{"result":"error","msg":"Record not deleted"}`
+		}
+		return `{"result":"success","response":{"rec":{"obj":{"rec_id":"1"}}}}`
+	})
+	cf.Journal = NewUndoJournal(filepath.Join(t.TempDir(), "journal"))
+
+	if _, err := cf.DeleteDnsRecord("example.com", "1"); err == nil {
+		t.Fatal("failed delete returned no error")
+	}
+	if entries, _ := cf.Journal.Entries(); len(entries) > 0 {
+		t.Fatalf("failed delete journaled: %+v", entries)
+	}
+
+	if _, err := cf.EditDnsRecord("example.com", "1", map[string]string{"type": "A", "name": "www", "content": "192.0.2.2"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := cf.Journal.Entries()
+	if len(entries) != 1 || entries[0].Action != JOURNAL_EDIT || entries[0].Record.Content != "192.0.2.1" {
+		t.Fatalf("entries %+v, want the edit with the previous content", entries)
+	}
+
+	undone, err := cf.Undo(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 1 {
+		t.Errorf("undone %+v", undone)
+	}
+	want := []string{"rec_load_all", "rec_delete", "rec_load_all", "rec_edit", "rec_edit"}
+	if actions := server.actions(); !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions %v, want %v", actions, want)
+	}
+	server.mu.Lock()
+	reverted := server.requests[len(server.requests)-1]
+	server.mu.Unlock()
+	if reverted.Get("content") != "192.0.2.1" || reverted.Get("id") != "1" {
+		t.Errorf("undo sent %v", reverted)
+	}
+	if entries, _ := cf.Journal.Entries(); len(entries) > 0 {
+		t.Errorf("journal not emptied: %+v", entries)
+	}
+}
@@ -0,0 +1,533 @@
+package cloudflare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* In-memory implementation of the API, for tests */
+
+// Memory implements API on zones held in memory. Zones are added with
+// AddZone; requests on other zones fail as the API would.
+type Memory struct {
+	mu     sync.Mutex
+	zones  map[string]*memoryZone
+	nextId int
+}
+
+type memoryZone struct {
+	zone     ZoneLoad
+	records  []Record
+	settings Settings
+	access   map[string]string
+	recent   []Ip
+	stats    Stats
+	purged   []string
+}
+
+var _ API = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{zones: make(map[string]*memoryZone)}
+}
+
+// AddZone adds an active zone with default settings and no records.
+func (this *Memory) AddZone(name string) ZoneLoad {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if zone, ok := this.zones[name]; ok {
+		return zone.zone
+	}
+	zone := &memoryZone{
+		zone: ZoneLoad{
+			ZoneId:      this.newId(),
+			ZoneName:    name,
+			DisplayName: name,
+			ZoneStatus:  "V",
+		},
+		settings: Settings{
+			SecLvl:     SEC_LVL_MED,
+			CacheLevel: CACHE_LVL_AGG,
+			Async:      ROCKET_OFF,
+			Minify:     "0",
+		},
+		access: make(map[string]string),
+	}
+	this.zones[name] = zone
+	return zone.zone
+}
+
+// AddRecentIp adds an IP returned by GetRecentIps for domain.
+func (this *Memory) AddRecentIp(domain string, ip Ip) error {
+	return this.update(domain, func(zone *memoryZone) error {
+		ip.ZoneName = zone.zone.ZoneName
+		zone.recent = append(zone.recent, ip)
+		return nil
+	})
+}
+
+// SetStats sets the stats returned by GetDomainStats for domain.
+func (this *Memory) SetStats(domain string, stats Stats) error {
+	return this.update(domain, func(zone *memoryZone) error {
+		zone.stats = stats
+		return nil
+	})
+}
+
+// Access returns the IP access rules of domain, sorted by IP.
+func (this *Memory) Access(domain string) ([]AccessRule, error) {
+	var rules []AccessRule
+	err := this.update(domain, func(zone *memoryZone) error {
+		for ip, action := range zone.access {
+			rules = append(rules, AccessRule{Ip: ip, Action: action})
+		}
+		return nil
+	})
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Ip < rules[j].Ip
+	})
+	return rules, err
+}
+
+// Purged returns the URLs purged from domain, "*" standing for the whole
+// cache.
+func (this *Memory) Purged(domain string) ([]string, error) {
+	var purged []string
+	err := this.update(domain, func(zone *memoryZone) error {
+		purged = append(purged, zone.purged...)
+		return nil
+	})
+	return purged, err
+}
+
+func (this *Memory) newId() string {
+	this.nextId++
+	return strconv.Itoa(this.nextId)
+}
+
+// update calls fn on domain with the memory locked.
+func (this *Memory) update(domain string, fn func(zone *memoryZone) error) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	zone, ok := this.zones[strings.TrimSuffix(strings.ToLower(domain), ".")]
+	if !ok {
+		return fmt.Errorf("unknown zone %q", domain)
+	}
+	return fn(zone)
+}
+
+/* Zones */
+
+func (this *Memory) GetDomainsList() (RootZones, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	data := RootZones{Result: "success"}
+	for _, zone := range this.zones {
+		data.Response.Zones.Objs = append(data.Response.Zones.Objs, zone.zone)
+	}
+	sort.Slice(data.Response.Zones.Objs, func(i, j int) bool {
+		return data.Response.Zones.Objs[i].ZoneName < data.Response.Zones.Objs[j].ZoneName
+	})
+	data.Response.Zones.Count = len(data.Response.Zones.Objs)
+	return data, nil
+}
+
+func (this *Memory) GetActiveZones(domain string, zones ...string) (RootZonesCheck, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	data := RootZonesCheck{Result: "success"}
+	data.Response.Zones = make(map[string]int)
+	for _, name := range zones {
+		id := 0
+		if zone, ok := this.zones[strings.ToLower(name)]; ok {
+			id, _ = strconv.Atoi(zone.zone.ZoneId)
+		}
+		data.Response.Zones[name] = id
+	}
+	return data, nil
+}
+
+func (this *Memory) Snapshot(domain, zoneid string) (Root, error) {
+	err := this.update(domain, func(zone *memoryZone) error {
+		if zoneid != "" && zoneid != zone.zone.ZoneId {
+			return fmt.Errorf("zone id %s is not %s", zoneid, domain)
+		}
+		return nil
+	})
+	if err != nil {
+		return Root{}, err
+	}
+	return Root{Result: "success"}, nil
+}
+
+/* Records */
+
+func (this *Memory) GetDnsRecords(domain string) (RootDnsRecords, error) {
+	data := RootDnsRecords{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		data.Response.Objs = append([]Record(nil), zone.records...)
+		data.Response.Count = len(zone.records)
+		return nil
+	})
+	if err != nil {
+		return RootDnsRecords{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) NewDnsRecord(domain string, values map[string]string) (RootNewRecord, error) {
+	data := RootNewRecord{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		record := Record{Id: this.newId(), ServiceMode: "0"}
+		if err := applyRecordValues(&record, zone.zone.ZoneName, values); err != nil {
+			return err
+		}
+		zone.records = append(zone.records, record)
+		data.Response.Rec = record
+		return nil
+	})
+	if err != nil {
+		return RootNewRecord{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) EditDnsRecord(domain, id string, values map[string]string) (RootEditRecord, error) {
+	data := RootEditRecord{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		for i := range zone.records {
+			if zone.records[i].Id != id {
+				continue
+			}
+			record := zone.records[i]
+			if err := applyRecordValues(&record, zone.zone.ZoneName, values); err != nil {
+				return err
+			}
+			zone.records[i] = record
+			data.Response.Rec = record
+			return nil
+		}
+		return fmt.Errorf("record %s not found in %s", id, domain)
+	})
+	if err != nil {
+		return RootEditRecord{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) DeleteDnsRecord(domain, id string) (Root, error) {
+	err := this.update(domain, func(zone *memoryZone) error {
+		for i := range zone.records {
+			if zone.records[i].Id == id {
+				zone.records = append(zone.records[:i], zone.records[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("record %s not found in %s", id, domain)
+	})
+	if err != nil {
+		return Root{}, err
+	}
+	return Root{Result: "success"}, nil
+}
+
+func (this *Memory) SetProxyStatus(domain, id string, status bool) (RootEditRecord, error) {
+	proxy_status := "0"
+	if status {
+		proxy_status = "1"
+	}
+	return this.EditDnsRecord(domain, id, map[string]string{"service_mode": proxy_status})
+}
+
+// applyRecordValues sets the fields of record from the arguments of
+// rec_new or rec_edit.
+func applyRecordValues(record *Record, zone string, values map[string]string) error {
+	if name, ok := values["name"]; ok {
+		record.Name = desiredName(name, zone)
+	}
+	if rtype, ok := values["type"]; ok {
+		record.Type = strings.ToUpper(rtype)
+	}
+	if content, ok := values["content"]; ok {
+		record.Content = content
+	}
+	if record.Type == "SRV" && values["target"] != "" {
+		record.Name = desiredName(values["service"]+"."+values["protocol"]+"."+values["srvname"], zone)
+		record.Content = values["weight"] + " " + values["port"] + " " + values["target"]
+	}
+	if ttl, ok := values["ttl"]; ok {
+		record.Ttl = ttl
+	}
+	if prio, ok := values["prio"]; ok {
+		record.Prio = prio
+	}
+	if mode, ok := values["service_mode"]; ok {
+		record.ServiceMode = mode
+	}
+	if record.Name == "" || record.Type == "" || record.Content == "" {
+		return fmt.Errorf("record needs a name, a type and content")
+	}
+
+	record.ZoneName = zone
+	record.DisplayName = relativeName(record.Name, zone)
+	if record.DisplayName == "@" {
+		record.DisplayName = record.Name
+	}
+	record.DisplayContent = record.Content
+	record.AutoTtl = 0
+	if record.Ttl == "" || record.Ttl == "1" {
+		record.Ttl = "1"
+		record.AutoTtl = 1
+	}
+	record.Props.Proxiable = 0
+	if record.Type == "A" || record.Type == "AAAA" || record.Type == "CNAME" {
+		record.Props.Proxiable = 1
+	}
+	record.Props.CloudOn = 0
+	if record.ServiceMode == "1" {
+		record.Props.CloudOn = 1
+	}
+	return nil
+}
+
+/* Settings */
+
+func (this *Memory) GetZoneSettings(domain string) (RootZoneSettings, error) {
+	data := RootZoneSettings{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		data.Response.Result = []Settings{zone.settings}
+		return nil
+	})
+	if err != nil {
+		return RootZoneSettings{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) SetSecurityLevel(domain, level string) (RootZones, error) {
+	data, err := this.UpdateSecurityLevel(domain, level)
+	return data.Zones(), err
+}
+
+func (this *Memory) UpdateSecurityLevel(domain, level string) (RootSecLevel, error) {
+	data := RootSecLevel{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		if err := validateChoice("security level", level, SEC_LVL_HELP, SEC_LVL_HIGH, SEC_LVL_MED, SEC_LVL_LOW, SEC_LVL_EOFF); err != nil {
+			return err
+		}
+		zone.settings.SecLvl = level
+		data.Response.Zone = zone.zone
+		return nil
+	})
+	if err != nil {
+		return RootSecLevel{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) SetCacheLevel(domain, level string) (RootZones, error) {
+	data, err := this.UpdateCacheLevel(domain, level)
+	return data.Zones(), err
+}
+
+func (this *Memory) UpdateCacheLevel(domain, level string) (RootCacheLevel, error) {
+	data := RootCacheLevel{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		if err := validateChoice("cache level", level, CACHE_LVL_AGG, CACHE_LVL_BAS); err != nil {
+			return err
+		}
+		zone.settings.CacheLevel = level
+		data.Response.Zone = zone.zone
+		return nil
+	})
+	if err != nil {
+		return RootCacheLevel{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) SetDevMode(domain string, enable bool) (time.Time, error) {
+	data, err := this.ToggleDevMode(domain, enable)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return data.Response.Expires(), nil
+}
+
+// ToggleDevMode turns development mode on for three hours, as Cloudflare
+// does, or off.
+func (this *Memory) ToggleDevMode(domain string, enable bool) (RootDevMode, error) {
+	data := RootDevMode{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		zone.settings.DevMode = 0
+		if enable {
+			expires := time.Now().Add(3 * time.Hour).Unix()
+			zone.settings.DevMode = int(expires)
+			data.Response.ExpiresOn = float64(expires)
+		}
+		data.Response.Zone = zone.zone
+		return nil
+	})
+	if err != nil {
+		return RootDevMode{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) ToggleIpv46(domain string, toggle bool) (Root, error) {
+	return this.setting(domain, func(settings *Settings) error {
+		settings.Ipv46 = 0
+		if toggle {
+			settings.Ipv46 = 3
+		}
+		return nil
+	})
+}
+
+// ToggleMirage2 only checks the zone as Mirage 2 is not part of the zone
+// settings.
+func (this *Memory) ToggleMirage2(domain string, toggle bool) (Root, error) {
+	return this.setting(domain, func(settings *Settings) error {
+		return nil
+	})
+}
+
+func (this *Memory) Minify(domain, state string) (Root, error) {
+	return this.setting(domain, func(settings *Settings) error {
+		if err := validateChoice("minify", state, "0", "1", "2", "3", "4", "5", "6", "7"); err != nil {
+			return err
+		}
+		settings.Minify = state
+		return nil
+	})
+}
+
+func (this *Memory) SetRocketLoader(domain, state string) (Root, error) {
+	return this.setting(domain, func(settings *Settings) error {
+		if err := validateChoice("rocket loader", state, ROCKET_OFF, ROCKET_AUTO, ROCKET_MANUAL); err != nil {
+			return err
+		}
+		settings.Async = state
+		return nil
+	})
+}
+
+func (this *Memory) setting(domain string, fn func(settings *Settings) error) (Root, error) {
+	err := this.update(domain, func(zone *memoryZone) error {
+		return fn(&zone.settings)
+	})
+	if err != nil {
+		return Root{}, err
+	}
+	return Root{Result: "success"}, nil
+}
+
+/* Cache */
+
+func (this *Memory) PurgeCache(domain string) (RootPurgeCache, error) {
+	data := RootPurgeCache{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		zone.purged = append(zone.purged, "*")
+		data.Response.FpurgeTs = float64(time.Now().Unix())
+		data.Response.Zone = zone.zone
+		return nil
+	})
+	if err != nil {
+		return RootPurgeCache{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) PurgeFile(domain, url_file string) (RootPurgeFile, error) {
+	err := this.update(domain, func(zone *memoryZone) error {
+		zone.purged = append(zone.purged, url_file)
+		return nil
+	})
+	if err != nil {
+		return RootPurgeFile{}, err
+	}
+	return RootPurgeFile{Result: "success", Response: PurgeFile{Url: url_file}}, nil
+}
+
+/* Firewall */
+
+// LookupIp returns the access rule of ip, empty when it has none.
+func (this *Memory) LookupIp(domain, ip string) (RootLookupIp, error) {
+	data := RootLookupIp{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		data.Response.Ip = zone.access[ip]
+		return nil
+	})
+	if err != nil {
+		return RootLookupIp{}, err
+	}
+	return data, nil
+}
+
+func (this *Memory) DenyIP(domain, ip string) (RootModIp, error) {
+	return this.access(domain, ip, IP_BAN)
+}
+
+func (this *Memory) AllowIP(domain, ip string) (RootModIp, error) {
+	return this.access(domain, ip, IP_ALLOW)
+}
+
+func (this *Memory) ForgetIP(domain, ip string) (RootModIp, error) {
+	return this.access(domain, ip, IP_FORGET)
+}
+
+func (this *Memory) access(domain, ip, action string) (RootModIp, error) {
+	err := this.update(domain, func(zone *memoryZone) error {
+		if action == IP_FORGET {
+			delete(zone.access, ip)
+		} else {
+			zone.access[ip] = action
+		}
+		return nil
+	})
+	if err != nil {
+		return RootModIp{}, err
+	}
+	return RootModIp{Result: "success", Response: ModIp{Ip: ip, Action: action}}, nil
+}
+
+/* Stats */
+
+func (this *Memory) GetDomainStats(domain, interval string) (RootStats, error) {
+	data := RootStats{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		data.Response = zone.stats
+		return nil
+	})
+	if err != nil {
+		return RootStats{}, err
+	}
+	return data, nil
+}
+
+// GetRecentIps returns the IPs added with AddRecentIp whose classification
+// starts with class; hours and geo are ignored.
+func (this *Memory) GetRecentIps(domain, hours, class, geo string) (RootZoneIps, error) {
+	data := RootZoneIps{Result: "success"}
+	err := this.update(domain, func(zone *memoryZone) error {
+		for _, ip := range zone.recent {
+			if strings.HasPrefix(ip.Classification, class) {
+				data.Response.Ips = append(data.Response.Ips, ip)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RootZoneIps{}, err
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package cloudflare
+
+import (
+	"sort"
+	"testing"
+)
+
+// newTestMemory returns a Memory holding the zone example.com.
+func newTestMemory() *Memory {
+	memory := NewMemory()
+	memory.AddZone("example.com")
+	return memory
+}
+
+// memoryRecords lists the records of domain as inputs, sorted by name and
+// type.
+func memoryRecords(t *testing.T, client RecordsAPI, domain string) []RecordInput {
+	records, err := client.GetDnsRecords(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []RecordInput
+	for _, record := range records.Response.Objs {
+		inputs = append(inputs, record.Input())
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].Name+" "+inputs[i].Type < inputs[j].Name+" "+inputs[j].Type
+	})
+	return inputs
+}
+
+func TestMemoryRecords(t *testing.T) {
+	memory := newTestMemory()
+
+	if _, err := memory.GetDnsRecords("example.org"); err == nil {
+		t.Error("unknown zone accepted")
+	}
+	if _, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www"}); err == nil {
+		t.Error("record without content accepted")
+	}
+
+	created, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www", "content": "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := created.Response.Rec
+	if record.Name != "www.example.com" || record.Ttl != "1" || record.AutoTtl != 1 || record.Props.Proxiable != 1 {
+		t.Errorf("created %+v", record)
+	}
+	proxied, err := memory.SetProxyStatus("example.com", record.Id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxied.Response.Rec.ServiceMode != "1" || proxied.Response.Rec.Props.CloudOn != 1 {
+		t.Errorf("proxied %+v", proxied.Response.Rec)
+	}
+	if _, err := memory.DeleteDnsRecord("example.com", record.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.DeleteDnsRecord("example.com", record.Id); err == nil {
+		t.Error("deleted record deleted again")
+	}
+}
+
+func TestMemorySettings(t *testing.T) {
+	memory := newTestMemory()
+
+	if _, err := memory.SetSecurityLevel("example.com", "paranoid"); err == nil {
+		t.Error("invalid security level accepted")
+	}
+	zones, err := memory.SetSecurityLevel("example.com", SEC_LVL_HIGH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zones.Response.Zones.Count != 1 || zones.Response.Zones.Objs[0].ZoneName != "example.com" {
+		t.Errorf("zones %+v", zones.Response.Zones)
+	}
+	settings, err := memory.GetZoneSettings("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := settings.Response.Result[0].SecLvl; level != SEC_LVL_HIGH {
+		t.Errorf("security level %s, want %s", level, SEC_LVL_HIGH)
+	}
+}
@@ -0,0 +1,137 @@
+package cloudflare
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+/* Batch purge of URLs */
+
+type PurgeOptions struct {
+	Workers   int
+	Threshold int
+}
+
+type PurgeResult struct {
+	Url string `json:"url"`
+	Err error  `json:"-"`
+}
+
+type PurgeReport struct {
+	Purged    []PurgeResult `json:"purged"`
+	Failed    []PurgeResult `json:"failed"`
+	Invalid   []PurgeResult `json:"invalid"`
+	FullPurge bool          `json:"full_purge"`
+}
+
+// NormalizePurgeUrl canonicalizes a URL of domain: the scheme defaults to
+// http, scheme and host are lowercased, default ports and fragments are
+// dropped and an empty path becomes "/". URLs of other hosts are rejected.
+func NormalizePurgeUrl(domain, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + strings.TrimPrefix(raw, "//")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", fmt.Errorf("%s is not in zone %s", host, domain)
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.User = nil
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
+
+// PurgeFiles purges urls from the cache of domain. URLs are normalized and
+// deduplicated, then purged concurrently with zone_file_purge. When more
+// than options.Threshold URLs remain the whole zone is purged instead; a
+// zero Threshold never falls back.
+func PurgeFiles(client CacheAPI, domain string, urls []string, options PurgeOptions) (PurgeReport, error) {
+	report := PurgeReport{}
+
+	var pending []string
+	seen := make(map[string]bool)
+	for _, raw := range urls {
+		normalized, err := NormalizePurgeUrl(domain, raw)
+		if err != nil {
+			report.Invalid = append(report.Invalid, PurgeResult{Url: raw, Err: err})
+			continue
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			pending = append(pending, normalized)
+		}
+	}
+
+	if options.Threshold > 0 && len(pending) > options.Threshold {
+		report.FullPurge = true
+		if _, err := client.PurgeCache(domain); err != nil {
+			for _, u := range pending {
+				report.Failed = append(report.Failed, PurgeResult{Url: u, Err: err})
+			}
+			return report, err
+		}
+		for _, u := range pending {
+			report.Purged = append(report.Purged, PurgeResult{Url: u})
+		}
+		return report, nil
+	}
+
+	results := make([]PurgeResult, len(pending))
+	runParallel(len(pending), options.Workers, false, func(i int) error {
+		_, err := client.PurgeFile(domain, pending[i])
+		results[i] = PurgeResult{Url: pending[i], Err: err}
+		return err
+	})
+	for _, result := range results {
+		if result.Err != nil {
+			report.Failed = append(report.Failed, result)
+		} else {
+			report.Purged = append(report.Purged, result)
+		}
+	}
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d of %d urls failed to purge", len(report.Failed), len(pending))
+	}
+	return report, nil
+}
+
+func (this PurgeReport) String() string {
+	s := fmt.Sprintf("%d purged, %d failed, %d invalid", len(this.Purged), len(this.Failed), len(this.Invalid))
+	if this.FullPurge {
+		s += " (full zone purge)"
+	}
+	s += "\n"
+	for _, result := range this.Failed {
+		s += fmt.Sprintf("  %s: %s\n", result.Url, result.Err)
+	}
+	for _, result := range this.Invalid {
+		s += fmt.Sprintf("  %s: %s\n", result.Url, result.Err)
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package cloudflare
+
+import (
+	"sync"
+	"time"
+)
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request slot.
+func (this *rateLimiter) wait() {
+	this.mu.Lock()
+	now := time.Now()
+	if this.next.Before(now) {
+		this.next = now
+	}
+	delay := this.next.Sub(now)
+	this.next = this.next.Add(this.interval)
+	this.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,17 @@
+package cloudflare
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectRateLimit(t *testing.T) {
+	cf := Connect("key", "user@example.com", false)
+	if cf.limiter == nil || cf.limiter.interval != CF_RATE_PERIOD/time.Duration(CF_RATE_LIMIT) {
+		t.Fatalf("limiter %+v, want the default budget", cf.limiter)
+	}
+	cf.SetRateLimit(0, 0)
+	if cf.limiter != nil {
+		t.Error("limit not removed")
+	}
+}
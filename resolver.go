@@ -0,0 +1,77 @@
+package cloudflare
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/* Zone name and id resolution */
+
+type zoneResolver struct {
+	mu    sync.Mutex
+	ids   map[string]string
+	names map[string]string
+}
+
+// ZoneId returns the id of the zone name, as listed by GetDomainsList. The
+// zone list is loaded once and reloaded when name is not in it.
+func (this *Cloudflare) ZoneId(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	return this.resolveZone(name, func(r *zoneResolver) string {
+		return r.ids[name]
+	})
+}
+
+// ZoneName returns the name of the zone with the given id.
+func (this *Cloudflare) ZoneName(id string) (string, error) {
+	return this.resolveZone(id, func(r *zoneResolver) string {
+		return r.names[id]
+	})
+}
+
+// RefreshZones reloads the zone names and ids.
+func (this *Cloudflare) RefreshZones() error {
+	this.resolver.mu.Lock()
+	defer this.resolver.mu.Unlock()
+	return this.loadZones()
+}
+
+func (this *Cloudflare) resolveZone(key string, lookup func(*zoneResolver) string) (string, error) {
+	this.resolver.mu.Lock()
+	defer this.resolver.mu.Unlock()
+
+	if this.resolver.ids != nil {
+		if found := lookup(&this.resolver); found != "" {
+			return found, nil
+		}
+	}
+	if err := this.loadZones(); err != nil {
+		return "", err
+	}
+	if found := lookup(&this.resolver); found != "" {
+		return found, nil
+	}
+	return "", fmt.Errorf("unknown zone %q", key)
+}
+
+// loadZones must be called with the resolver locked. The cached zone list
+// is dropped first, a reload is only needed when it is stale.
+func (this *Cloudflare) loadZones() error {
+	this.InvalidateAction("zone_load_multi")
+	zones, err := this.GetDomainsList()
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]string)
+	names := make(map[string]string)
+	for _, zone := range zones.Response.Zones.Objs {
+		name := strings.TrimSuffix(strings.ToLower(zone.ZoneName), ".")
+		ids[name] = zone.ZoneId
+		names[zone.ZoneId] = name
+	}
+	this.resolver.ids = ids
+	this.resolver.names = names
+	return nil
+}
@@ -0,0 +1,34 @@
+package cloudflare
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolverReloadsCachedZones(t *testing.T) {
+	zones := []string{`{"zone_id":"1","zone_name":"a.com"}`}
+	client, server := newTestClient(t, func(url.Values) string {
+		return `{"result":"success","response":{"zones":{"count":1,"objs":[` + strings.Join(zones, ",") + `]}}}`
+	})
+	client.EnableCache(nil)
+
+	if id, err := client.ZoneId("A.com."); err != nil || id != "1" {
+		t.Fatalf("ZoneId(a.com) = %q, %v", id, err)
+	}
+	zones = append(zones, `{"zone_id":"2","zone_name":"b.com"}`)
+	if id, err := client.ZoneId("b.com"); err != nil || id != "2" {
+		t.Fatalf("ZoneId(b.com) = %q, %v", id, err)
+	}
+	if name, err := client.ZoneName("2"); err != nil || name != "b.com" {
+		t.Errorf("ZoneName(2) = %q, %v", name, err)
+	}
+	if _, err := client.ZoneId("c.com"); err == nil {
+		t.Error("unknown zone resolved")
+	}
+	want := []string{"zone_load_multi", "zone_load_multi", "zone_load_multi"}
+	if actions := server.actions(); !reflect.DeepEqual(actions, want) {
+		t.Errorf("requests %v, want %v", actions, want)
+	}
+}
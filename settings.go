@@ -0,0 +1,188 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	SEC_LVL_HELP  string = "help"
+	SEC_LVL_HIGH  string = "high"
+	SEC_LVL_MED   string = "med"
+	SEC_LVL_LOW   string = "low"
+	SEC_LVL_EOFF  string = "eoff"
+	CACHE_LVL_AGG string = "agg"
+	CACHE_LVL_BAS string = "basic"
+	ROCKET_OFF    string = "0"
+	ROCKET_AUTO   string = "a"
+	ROCKET_MANUAL string = "m"
+	MINIFY_JS     int    = 1
+	MINIFY_CSS    int    = 2
+	MINIFY_HTML   int    = 4
+)
+
+/* Typed zone settings */
+
+// ZoneConfig is a typed view of the zone settings that can be changed
+// through the API. Nil fields are unknown or left untouched. Mirage2 is
+// not part of the zone_settings response: it is never read back and
+// ApplyZoneConfig sends it whenever it is set.
+type ZoneConfig struct {
+	SecurityLevel *string `json:"security_level,omitempty"`
+	CacheLevel    *string `json:"cache_level,omitempty"`
+	DevMode       *bool   `json:"dev_mode,omitempty"`
+	Ipv6          *bool   `json:"ipv6,omitempty"`
+	RocketLoader  *string `json:"rocket_loader,omitempty"`
+	Minify        *int    `json:"minify,omitempty"`
+	Mirage2       *bool   `json:"mirage2,omitempty"`
+}
+
+func String(s string) *string {
+	return &s
+}
+
+func Bool(b bool) *bool {
+	return &b
+}
+
+func Int(i int) *int {
+	return &i
+}
+
+// Typed converts the raw settings returned by zone_settings.
+func (this Settings) Typed() ZoneConfig {
+	config := ZoneConfig{
+		DevMode: Bool(this.DevMode != 0),
+		Ipv6:    Bool(this.Ipv46 == 3),
+	}
+	if this.SecLvl != "" {
+		config.SecurityLevel = String(this.SecLvl)
+	}
+	if this.CacheLevel != "" {
+		config.CacheLevel = String(this.CacheLevel)
+	}
+	if this.Async != "" {
+		config.RocketLoader = String(this.Async)
+	}
+	if minify, err := strconv.Atoi(this.Minify); err == nil {
+		config.Minify = Int(minify)
+	}
+	return config
+}
+
+// ParseZoneConfig reads a partial ZoneConfig in JSON.
+func ParseZoneConfig(data []byte) (ZoneConfig, error) {
+	config := ZoneConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ZoneConfig{}, err
+	}
+	return config, nil
+}
+
+// GetZoneConfig returns the current settings of domain as a ZoneConfig.
+func GetZoneConfig(client SettingsAPI, domain string) (ZoneConfig, error) {
+	settings, err := client.GetZoneSettings(domain)
+	if err != nil {
+		return ZoneConfig{}, err
+	}
+	if len(settings.Response.Result) == 0 {
+		return ZoneConfig{}, fmt.Errorf("%s: no zone settings returned", domain)
+	}
+	return settings.Response.Result[0].Typed(), nil
+}
+
+// ApplyZoneConfig sends the settings of want that differ from the current
+// ones, each through its own action, and returns the names of the settings
+// changed.
+func ApplyZoneConfig(client SettingsAPI, domain string, want ZoneConfig) ([]string, error) {
+	have, err := GetZoneConfig(client, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []struct {
+		name    string
+		differs bool
+		set     func() error
+	}{
+		{"security_level", stringChanged(want.SecurityLevel, have.SecurityLevel), func() error {
+			_, err := client.UpdateSecurityLevel(domain, *want.SecurityLevel)
+			return err
+		}},
+		{"cache_level", stringChanged(want.CacheLevel, have.CacheLevel), func() error {
+			_, err := client.UpdateCacheLevel(domain, *want.CacheLevel)
+			return err
+		}},
+		{"dev_mode", boolChanged(want.DevMode, have.DevMode), func() error {
+			_, err := client.SetDevMode(domain, *want.DevMode)
+			return err
+		}},
+		{"ipv6", boolChanged(want.Ipv6, have.Ipv6), func() error {
+			_, err := client.ToggleIpv46(domain, *want.Ipv6)
+			return err
+		}},
+		{"rocket_loader", stringChanged(want.RocketLoader, have.RocketLoader), func() error {
+			_, err := client.SetRocketLoader(domain, *want.RocketLoader)
+			return err
+		}},
+		{"minify", intChanged(want.Minify, have.Minify), func() error {
+			_, err := client.Minify(domain, strconv.Itoa(*want.Minify))
+			return err
+		}},
+		{"mirage2", boolChanged(want.Mirage2, have.Mirage2), func() error {
+			_, err := client.ToggleMirage2(domain, *want.Mirage2)
+			return err
+		}},
+	}
+
+	var changed []string
+	for _, step := range steps {
+		if !step.differs {
+			continue
+		}
+		if err := step.set(); err != nil {
+			return changed, fmt.Errorf("%s: %s", step.name, err)
+		}
+		changed = append(changed, step.name)
+	}
+	return changed, nil
+}
+
+func stringChanged(want, have *string) bool {
+	return want != nil && (have == nil || *want != *have)
+}
+
+func boolChanged(want, have *bool) bool {
+	return want != nil && (have == nil || *want != *have)
+}
+
+func intChanged(want, have *int) bool {
+	return want != nil && (have == nil || *want != *have)
+}
+
+/* Compatibility with the RootZones results of earlier versions */
+
+// Zones wraps the updated zone as SetSecurityLevel returns it.
+func (this RootSecLevel) Zones() RootZones {
+	return zonesResult(this.Result, this.Message, this.Response.Zone)
+}
+
+// Zones wraps the updated zone as SetCacheLevel returns it.
+func (this RootCacheLevel) Zones() RootZones {
+	return zonesResult(this.Result, this.Message, this.Response.Zone)
+}
+
+// Zones wraps the updated zone as SetDevMode used to return it.
+func (this RootDevMode) Zones() RootZones {
+	return zonesResult(this.Result, this.Message, this.Response.Zone)
+}
+
+func zonesResult(result, message string, zone ZoneLoad) RootZones {
+	data := RootZones{Result: result, Message: message}
+	if zone.ZoneId != "" || zone.ZoneName != "" {
+		data.Response.Zones.Count = 1
+		data.Response.Zones.Objs = []ZoneLoad{zone}
+	}
+	return data
+}
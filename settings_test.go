@@ -0,0 +1,63 @@
+package cloudflare
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSettingsTyped(t *testing.T) {
+	config := Settings{SecLvl: SEC_LVL_HIGH, CacheLevel: CACHE_LVL_BAS, DevMode: 1424000000, Ipv46: 3, Async: ROCKET_AUTO, Minify: "5"}.Typed()
+	want := ZoneConfig{
+		SecurityLevel: String(SEC_LVL_HIGH),
+		CacheLevel:    String(CACHE_LVL_BAS),
+		DevMode:       Bool(true),
+		Ipv6:          Bool(true),
+		RocketLoader:  String(ROCKET_AUTO),
+		Minify:        Int(MINIFY_JS | MINIFY_HTML),
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v\nwant %+v", config, want)
+	}
+
+	config = Settings{Ipv46: 0, Minify: ""}.Typed()
+	if *config.DevMode || *config.Ipv6 || config.SecurityLevel != nil || config.Minify != nil {
+		t.Errorf("empty settings: %+v", config)
+	}
+}
+
+func TestApplyZoneConfigOnMemory(t *testing.T) {
+	memory := newTestMemory()
+
+	want := ZoneConfig{SecurityLevel: String(SEC_LVL_HIGH), CacheLevel: String(CACHE_LVL_AGG)}
+	changed, err := ApplyZoneConfig(memory, "example.com", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"security_level"}) {
+		t.Errorf("changed %v, want [security_level]", changed)
+	}
+	have, err := GetZoneConfig(memory, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *have.SecurityLevel != SEC_LVL_HIGH {
+		t.Errorf("security level %s", *have.SecurityLevel)
+	}
+	if changed, _ := ApplyZoneConfig(memory, "example.com", want); len(changed) > 0 {
+		t.Errorf("changed %v on second apply", changed)
+	}
+
+	// Mirage 2 cannot be read back and is sent each time.
+	want.Mirage2 = Bool(true)
+	for i := 0; i < 2; i++ {
+		if changed, _ := ApplyZoneConfig(memory, "example.com", want); !reflect.DeepEqual(changed, []string{"mirage2"}) {
+			t.Errorf("changed %v, want [mirage2]", changed)
+		}
+	}
+
+	changed, err = ApplyZoneConfig(memory, "example.com", ZoneConfig{Minify: Int(MINIFY_CSS), RocketLoader: String("sometimes")})
+	// Rocket loader is applied before minify and stops the others.
+	if err == nil || len(changed) > 0 {
+		t.Errorf("changed %v, error %v, want the invalid rocket loader state refused", changed, err)
+	}
+}
@@ -0,0 +1,199 @@
+package cloudflare
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	SITEMAP_MAX_DEPTH int = 4
+)
+
+/* Purge of the URLs listed in a sitemap */
+
+type SitemapEntry struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// SitemapFilter selects sitemap entries. Prefix is matched against the URL
+// path when it starts with "/" and against the whole URL otherwise. Entries
+// without lastmod are kept when Since is set.
+type SitemapFilter struct {
+	Prefix string
+	Since  time.Time
+}
+
+type sitemapDocument struct {
+	XMLName  xml.Name
+	Urls     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+var sitemapClient = &http.Client{Timeout: 30 * time.Second}
+
+// ReadSitemap loads a sitemap from a local file or an http(s) URL. Sitemap
+// indexes are followed and gzip compressed sitemaps are detected from
+// their content. Children of an index whose lastmod is before since are
+// not fetched.
+func ReadSitemap(location string, since time.Time) ([]SitemapEntry, error) {
+	return readSitemap(location, since, 0, make(map[string]bool))
+}
+
+func readSitemap(location string, since time.Time, depth int, seen map[string]bool) ([]SitemapEntry, error) {
+	if depth > SITEMAP_MAX_DEPTH {
+		return nil, fmt.Errorf("%s: sitemap indexes nested too deeply", location)
+	}
+	if seen[location] {
+		return nil, nil
+	}
+	seen[location] = true
+
+	body, err := openSitemap(location)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	document := sitemapDocument{}
+	reader, err := maybeGunzip(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", location, err)
+	}
+	if err := xml.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, fmt.Errorf("%s: %s", location, err)
+	}
+
+	var entries []SitemapEntry
+	for _, u := range document.Urls {
+		lastmod, err := parseLastMod(u.LastMod)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", location, err)
+		}
+		entries = append(entries, SitemapEntry{Loc: strings.TrimSpace(u.Loc), LastMod: lastmod})
+	}
+
+	for _, child := range document.Sitemaps {
+		lastmod, err := parseLastMod(child.LastMod)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", location, err)
+		}
+		if !since.IsZero() && !lastmod.IsZero() && lastmod.Before(since) {
+			continue
+		}
+		childEntries, err := readSitemap(resolveSitemap(location, strings.TrimSpace(child.Loc)), since, depth+1, seen)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, childEntries...)
+	}
+	return entries, nil
+}
+
+func openSitemap(location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+
+	response, err := sitemapClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("%s: %s", location, response.Status)
+	}
+	return response.Body, nil
+}
+
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+// resolveSitemap resolves the location of a child sitemap against the
+// index that lists it, which may be a local file.
+func resolveSitemap(parent, child string) string {
+	if strings.HasPrefix(child, "http://") || strings.HasPrefix(child, "https://") {
+		return child
+	}
+	if base, err := url.Parse(parent); err == nil && (base.Scheme == "http" || base.Scheme == "https") {
+		if ref, err := url.Parse(child); err == nil {
+			return base.ResolveReference(ref).String()
+		}
+	}
+	if filepath.IsAbs(child) {
+		return child
+	}
+	return filepath.Join(filepath.Dir(parent), child)
+}
+
+func parseLastMod(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid lastmod %q", s)
+}
+
+func FilterSitemap(entries []SitemapEntry, filter SitemapFilter) []SitemapEntry {
+	var kept []SitemapEntry
+	for _, entry := range entries {
+		if filter.Prefix != "" {
+			target := entry.Loc
+			if strings.HasPrefix(filter.Prefix, "/") {
+				u, err := url.Parse(entry.Loc)
+				if err != nil {
+					continue
+				}
+				target = u.Path
+			}
+			if !strings.HasPrefix(target, filter.Prefix) {
+				continue
+			}
+		}
+		if !filter.Since.IsZero() && !entry.LastMod.IsZero() && entry.LastMod.Before(filter.Since) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// PurgeSitemap purges every URL of the sitemap at location that passes
+// filter, using PurgeFiles.
+func PurgeSitemap(client CacheAPI, domain, location string, filter SitemapFilter, options PurgeOptions) (PurgeReport, error) {
+	entries, err := ReadSitemap(location, filter.Since)
+	if err != nil {
+		return PurgeReport{}, err
+	}
+
+	var urls []string
+	for _, entry := range FilterSitemap(entries, filter) {
+		urls = append(urls, entry.Loc)
+	}
+	return PurgeFiles(client, domain, urls, options)
+}
@@ -0,0 +1,133 @@
+package cloudflare
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const sitemapTestIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>pages.xml</loc><lastmod>2026-09-01</lastmod></sitemap>
+  <sitemap><loc>posts.xml.gz</loc></sitemap>
+  <sitemap><loc>archive.xml</loc><lastmod>2020-01-01T00:00:00Z</lastmod></sitemap>
+</sitemapindex>`
+
+const sitemapTestPages = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><lastmod>2026-09-01</lastmod></url>
+  <url><loc>https://example.com/blog/</loc><lastmod>2026-08-01</lastmod></url>
+</urlset>`
+
+const sitemapTestPosts = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/blog/new </loc><lastmod>2026-09-15T10:00:00+02:00</lastmod></url>
+  <url><loc>https://example.com/blog/old</loc><lastmod>2025-01-01</lastmod></url>
+  <url><loc>https://example.com/blog/undated</loc></url>
+</urlset>`
+
+func gzipped(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	writer := gzip.NewWriter(&b)
+	if _, err := writer.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestPurgeSitemapFromServer(t *testing.T) {
+	files := map[string][]byte{
+		"/sitemap.xml":  []byte(sitemapTestIndex),
+		"/pages.xml":    []byte(sitemapTestPages),
+		"/posts.xml.gz": gzipped(t, sitemapTestPosts),
+		"/archive.xml":  []byte(`<urlset><url><loc>https://example.com/archive</loc></url></urlset>`),
+	}
+	var mu sync.Mutex
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched = append(fetched, r.URL.Path)
+		mu.Unlock()
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	memory := newTestMemory()
+	since := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report, err := PurgeSitemap(memory, "example.com", server.URL+"/sitemap.xml", SitemapFilter{Prefix: "/blog/", Since: since}, PurgeOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Purged) != 3 {
+		t.Errorf("report %+v", report)
+	}
+	purged, _ := memory.Purged("example.com")
+	sort.Strings(purged)
+	want := []string{"https://example.com/blog/", "https://example.com/blog/new", "https://example.com/blog/undated"}
+	if !reflect.DeepEqual(purged, want) {
+		t.Errorf("purged %v, want %v", purged, want)
+	}
+	if want := []string{"/sitemap.xml", "/pages.xml", "/posts.xml.gz"}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched %v, want %v without the stale archive", fetched, want)
+	}
+
+	if _, err := PurgeSitemap(memory, "example.com", server.URL+"/missing.xml", SitemapFilter{}, PurgeOptions{}); err == nil {
+		t.Error("missing sitemap accepted")
+	}
+}
+
+func TestReadSitemapFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"sitemap.xml":  []byte(sitemapTestIndex),
+		"pages.xml":    []byte(sitemapTestPages),
+		"posts.xml.gz": gzipped(t, sitemapTestPosts),
+		"archive.xml":  []byte(`<urlset><url><loc>https://example.com/archive</loc></url></urlset>`),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := ReadSitemap(filepath.Join(dir, "sitemap.xml"), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, entry := range entries {
+		locs = append(locs, entry.Loc)
+	}
+	want := []string{
+		"https://example.com/",
+		"https://example.com/blog/",
+		"https://example.com/blog/new",
+		"https://example.com/blog/old",
+		"https://example.com/blog/undated",
+		"https://example.com/archive",
+	}
+	if !reflect.DeepEqual(locs, want) {
+		t.Errorf("got %v, want %v", locs, want)
+	}
+	if lastmod := entries[2].LastMod; !lastmod.Equal(time.Date(2026, 9, 15, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("lastmod %s", lastmod)
+	}
+
+	kept := FilterSitemap(entries, SitemapFilter{Prefix: "https://example.com/blog/o"})
+	if len(kept) != 1 || kept[0].Loc != "https://example.com/blog/old" {
+		t.Errorf("kept %+v", kept)
+	}
+}
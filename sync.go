@@ -0,0 +1,239 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const (
+	SYNC_CREATE string = "create"
+	SYNC_UPDATE string = "update"
+	SYNC_DELETE string = "delete"
+	SYNC_PROXY  string = "proxy"
+)
+
+/* Desired state of a zone */
+
+type DesiredZone struct {
+	Zone          string        `json:"zone"`
+	KeepUnmanaged bool          `json:"keep_unmanaged"`
+	Records       []RecordInput `json:"records"`
+}
+
+// ParseDesiredZone reads a desired zone description in JSON.
+// Record names may be relative to the zone, "@" or fully qualified.
+func ParseDesiredZone(data []byte) (DesiredZone, error) {
+	zone := DesiredZone{}
+	if err := json.Unmarshal(data, &zone); err != nil {
+		return DesiredZone{}, err
+	}
+	if zone.Zone == "" {
+		return DesiredZone{}, fmt.Errorf("desired zone has no zone name")
+	}
+
+	zone.Zone = strings.TrimSuffix(strings.ToLower(zone.Zone), ".")
+	for i, record := range zone.Records {
+		if record.Type == "" || record.Content == "" {
+			return DesiredZone{}, fmt.Errorf("record %d (%s) needs a type and content", i, record.Name)
+		}
+		record.Name = desiredName(record.Name, zone.Zone)
+		record.Type = strings.ToUpper(record.Type)
+		zone.Records[i] = record
+	}
+	return zone, nil
+}
+
+func LoadDesiredZone(path string) (DesiredZone, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return DesiredZone{}, err
+	}
+	return ParseDesiredZone(data)
+}
+
+func desiredName(name, zone string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "" || name == "@":
+		return zone
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case name == zone || strings.HasSuffix(name, "."+zone):
+		return name
+	}
+	return name + "." + zone
+}
+
+/* Plan of changes between desired and actual records */
+
+type Plan struct {
+	Zone    string   `json:"zone"`
+	Changes []Change `json:"changes"`
+}
+
+type Change struct {
+	Action  string      `json:"action"`
+	Record  Record      `json:"record"`
+	Desired RecordInput `json:"desired"`
+}
+
+func (this Change) String() string {
+	current := this.Record.Input()
+	switch this.Action {
+	case SYNC_CREATE:
+		return "+ " + this.Desired.String()
+	case SYNC_DELETE:
+		return "- " + current.String()
+	case SYNC_PROXY:
+		state := "off"
+		if this.Desired.Proxied {
+			state = "on"
+		}
+		return fmt.Sprintf("~ %s %s %s: proxy %s", current.Name, current.Type, current.Content, state)
+	}
+
+	var fields []string
+	if current.Content != this.Desired.Content {
+		fields = append(fields, fmt.Sprintf("content %s -> %s", current.Content, this.Desired.Content))
+	}
+	if current.Ttl != this.Desired.Ttl {
+		fields = append(fields, fmt.Sprintf("ttl %d -> %d", current.Ttl, this.Desired.Ttl))
+	}
+	if current.Prio != this.Desired.Prio {
+		fields = append(fields, fmt.Sprintf("prio %d -> %d", current.Prio, this.Desired.Prio))
+	}
+	if current.Proxied != this.Desired.Proxied {
+		fields = append(fields, fmt.Sprintf("proxied %t -> %t", current.Proxied, this.Desired.Proxied))
+	}
+	return fmt.Sprintf("~ %s %s: %s", current.Name, current.Type, strings.Join(fields, ", "))
+}
+
+func (this Plan) String() string {
+	if len(this.Changes) == 0 {
+		return this.Zone + ": no changes\n"
+	}
+
+	counts := make(map[string]int)
+	var b strings.Builder
+	for _, change := range this.Changes {
+		counts[change.Action]++
+		b.WriteString(change.String())
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%s: %d to create, %d to update, %d to delete, %d proxy changes\n",
+		this.Zone, counts[SYNC_CREATE], counts[SYNC_UPDATE], counts[SYNC_DELETE], counts[SYNC_PROXY])
+	return b.String()
+}
+
+// PlanDnsSync compares desired against the records currently in the zone.
+// Records are matched on name, type and content; leftovers with the same
+// name and type are paired as content updates.
+func PlanDnsSync(client RecordsAPI, desired DesiredZone) (Plan, error) {
+	records, err := client.GetDnsRecords(desired.Zone)
+	if err != nil {
+		return Plan{}, err
+	}
+	return planDnsSync(desired, records.Response.Objs), nil
+}
+
+func planDnsSync(desired DesiredZone, actual []Record) Plan {
+	plan := Plan{Zone: desired.Zone}
+
+	existing := make(map[string][]Record)
+	for _, record := range actual {
+		key := syncKey(record.Name, record.Type, record.Content)
+		existing[key] = append(existing[key], record)
+	}
+
+	var missing []RecordInput
+	for _, want := range desired.Records {
+		key := syncKey(want.Name, want.Type, want.Content)
+		matches := existing[key]
+		if len(matches) == 0 {
+			missing = append(missing, want)
+			continue
+		}
+		have := matches[0]
+		existing[key] = matches[1:]
+
+		current := have.Input()
+		switch {
+		case current.Ttl != want.Ttl || current.Prio != want.Prio:
+			plan.Changes = append(plan.Changes, Change{Action: SYNC_UPDATE, Record: have, Desired: want})
+		case current.Proxied != want.Proxied:
+			plan.Changes = append(plan.Changes, Change{Action: SYNC_PROXY, Record: have, Desired: want})
+		}
+	}
+
+	var leftovers []Record
+	for _, record := range actual {
+		key := syncKey(record.Name, record.Type, record.Content)
+		for _, left := range existing[key] {
+			if left.Id == record.Id {
+				leftovers = append(leftovers, record)
+				break
+			}
+		}
+	}
+
+	for _, want := range missing {
+		paired := false
+		for i, left := range leftovers {
+			if syncKey(left.Name, left.Type, "") == syncKey(want.Name, want.Type, "") {
+				plan.Changes = append(plan.Changes, Change{Action: SYNC_UPDATE, Record: left, Desired: want})
+				leftovers = append(leftovers[:i], leftovers[i+1:]...)
+				paired = true
+				break
+			}
+		}
+		if !paired {
+			plan.Changes = append(plan.Changes, Change{Action: SYNC_CREATE, Desired: want})
+		}
+	}
+
+	if !desired.KeepUnmanaged {
+		for _, left := range leftovers {
+			plan.Changes = append(plan.Changes, Change{Action: SYNC_DELETE, Record: left})
+		}
+	}
+
+	order := map[string]int{SYNC_DELETE: 0, SYNC_UPDATE: 1, SYNC_PROXY: 2, SYNC_CREATE: 3}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return order[plan.Changes[i].Action] < order[plan.Changes[j].Action]
+	})
+	return plan
+}
+
+func syncKey(name, rtype, content string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	content = strings.TrimSuffix(content, ".")
+	if rtype != "TXT" && rtype != "SPF" {
+		content = strings.ToLower(content)
+	}
+	return name + "|" + strings.ToUpper(rtype) + "|" + content
+}
+
+// ApplyPlan performs the changes of plan in order and stops at the first
+// failure.
+func ApplyPlan(client RecordsAPI, plan Plan) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case SYNC_CREATE:
+			_, err = client.NewDnsRecord(plan.Zone, change.Desired.Values())
+		case SYNC_UPDATE, SYNC_PROXY:
+			_, err = client.EditDnsRecord(plan.Zone, change.Record.Id, change.Desired.Values())
+		case SYNC_DELETE:
+			_, err = client.DeleteDnsRecord(plan.Zone, change.Record.Id)
+		default:
+			err = fmt.Errorf("unknown action %q", change.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", change, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cloudflare
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseDesiredZone(t *testing.T) {
+	zone, err := ParseDesiredZone([]byte(`{
+	"zone": "Example.com.",
+	"records": [
+		{"name": "2024", "type": "txt", "content": "12345"},
+		{"name": "@", "type": "MX", "content": "mx1.example.net", "prio": 10, "ttl": 3600},
+		{"name": "www.example.com", "type": "CNAME", "content": "example.com", "proxied": true},
+		{"name": "ext.other.net.", "type": "A", "content": "192.0.2.1"}
+	]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DesiredZone{
+		Zone: "example.com",
+		Records: []RecordInput{
+			{Name: "2024.example.com", Type: "TXT", Content: "12345"},
+			{Name: "example.com", Type: "MX", Content: "mx1.example.net", Prio: 10, Ttl: 3600},
+			{Name: "www.example.com", Type: "CNAME", Content: "example.com", Proxied: true},
+			{Name: "ext.other.net", Type: "A", Content: "192.0.2.1"},
+		},
+	}
+	if !reflect.DeepEqual(zone, want) {
+		t.Errorf("got %+v\nwant %+v", zone, want)
+	}
+
+	for _, input := range []string{
+		"zone: example.com\n",
+		`{"records": []}`,
+		`{"zone": "example.com", "records": [{"name": "www", "type": "A"}]}`,
+		`{"zone": "example.com", "records": [{"name": "www", "type": "A", "content": "192.0.2.1", "ttl": "300"}]}`,
+	} {
+		if _, err := ParseDesiredZone([]byte(input)); err == nil {
+			t.Errorf("%s: accepted", input)
+		}
+	}
+}
+
+func TestSyncOnMemory(t *testing.T) {
+	memory := newTestMemory()
+	if _, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "old", "content": "192.0.2.9"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.NewDnsRecord("example.com", map[string]string{"type": "A", "name": "www", "content": "192.0.2.1", "ttl": "300"}); err != nil {
+		t.Fatal(err)
+	}
+
+	desired := DesiredZone{
+		Zone: "example.com",
+		Records: []RecordInput{
+			{Name: "example.com", Type: "MX", Content: "mx1.example.net", Prio: 10, Ttl: 3600},
+			{Name: "www.example.com", Type: "A", Content: "192.0.2.1", Ttl: 600, Proxied: true},
+		},
+	}
+	plan, err := PlanDnsSync(memory, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, change := range plan.Changes {
+		actions = append(actions, change.Action)
+	}
+	sort.Strings(actions)
+	if want := []string{SYNC_CREATE, SYNC_DELETE, SYNC_UPDATE}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions %v, want %v", actions, want)
+	}
+	if err := ApplyPlan(memory, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := memoryRecords(t, memory, "example.com"); !reflect.DeepEqual(got, desired.Records) {
+		t.Errorf("records\n got %+v\nwant %+v", got, desired.Records)
+	}
+	plan, err = PlanDnsSync(memory, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) > 0 {
+		t.Errorf("changes after apply: %v", plan.Changes)
+	}
+
+	desired.KeepUnmanaged = true
+	desired.Records = desired.Records[:1]
+	if plan, _ := PlanDnsSync(memory, desired); len(plan.Changes) > 0 {
+		t.Errorf("unmanaged records changed: %v", plan.Changes)
+	}
+}
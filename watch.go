@@ -0,0 +1,157 @@
+package cloudflare
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	EVENT_CREATED string = "created"
+	EVENT_UPDATED string = "updated"
+	EVENT_DELETED string = "deleted"
+	EVENT_ERROR   string = "error"
+)
+
+/* Record change events from polling */
+
+// RecordEvent is a change of a record between two polls. Old is the record
+// before an update and Fields lists what changed. Error events carry Err
+// and no record.
+type RecordEvent struct {
+	Type   string        `json:"type"`
+	Zone   string        `json:"zone"`
+	Time   time.Time     `json:"time"`
+	Record Record        `json:"record"`
+	Old    Record        `json:"old"`
+	Fields []FieldChange `json:"fields,omitempty"`
+	Err    error         `json:"-"`
+}
+
+func (this RecordEvent) String() string {
+	switch this.Type {
+	case EVENT_ERROR:
+		return fmt.Sprintf("%s: error: %s", this.Zone, this.Err)
+	case EVENT_UPDATED:
+		var fields []string
+		for _, field := range this.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s -> %s", field.Field, field.Old, field.New))
+		}
+		return fmt.Sprintf("%s: %s %s %s %s: %s", this.Zone, this.Type, this.Record.Id, this.Record.Name, this.Record.Type, strings.Join(fields, ", "))
+	}
+	return fmt.Sprintf("%s: %s %s %s %s %s", this.Zone, this.Type, this.Record.Id, this.Record.Name, this.Record.Type, this.Record.Content)
+}
+
+type RecordWatcher struct {
+	Client   RecordsAPI
+	Zone     string
+	Interval time.Duration
+}
+
+// Watch polls the records of Zone every Interval and sends the changes
+// between successive polls, matched by record id, on the returned channel.
+// The first poll only sets the initial state. Failed polls are sent as
+// error events and do not change the state. The channel is closed once
+// stop is closed.
+func (this *RecordWatcher) Watch(stop <-chan struct{}) <-chan RecordEvent {
+	events := make(chan RecordEvent)
+	go this.run(events, stop)
+	return events
+}
+
+func (this *RecordWatcher) run(events chan<- RecordEvent, stop <-chan struct{}) {
+	defer close(events)
+
+	interval := this.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous []Record
+	for {
+		records, err := this.Client.GetDnsRecords(this.Zone)
+		now := time.Now()
+		if err != nil {
+			if !this.send(events, stop, RecordEvent{Type: EVENT_ERROR, Zone: this.Zone, Time: now, Err: err}) {
+				return
+			}
+		} else {
+			if previous != nil {
+				for _, event := range recordEvents(this.Zone, now, previous, records.Response.Objs) {
+					if !this.send(events, stop, event) {
+						return
+					}
+				}
+			}
+			previous = append([]Record{}, records.Response.Objs...)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *RecordWatcher) send(events chan<- RecordEvent, stop <-chan struct{}, event RecordEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// recordEvents lists the deletions of the records of previous missing from
+// current, then the creations and updates in the order of current.
+func recordEvents(zone string, now time.Time, previous, current []Record) []RecordEvent {
+	var events []RecordEvent
+
+	before := make(map[string]Record)
+	for _, record := range previous {
+		before[record.Id] = record
+	}
+	seen := make(map[string]bool)
+	for _, record := range current {
+		seen[record.Id] = true
+	}
+
+	for _, record := range previous {
+		if !seen[record.Id] {
+			events = append(events, RecordEvent{Type: EVENT_DELETED, Zone: zone, Time: now, Record: record})
+		}
+	}
+	for _, record := range current {
+		old, ok := before[record.Id]
+		if !ok {
+			events = append(events, RecordEvent{Type: EVENT_CREATED, Zone: zone, Time: now, Record: record})
+			continue
+		}
+		if fields := watchedFields(old, record); len(fields) > 0 {
+			events = append(events, RecordEvent{Type: EVENT_UPDATED, Zone: zone, Time: now, Record: record, Old: old, Fields: fields})
+		}
+	}
+	return events
+}
+
+// watchedFields compares the fields diffFields compares plus the name,
+// type and content, which can change under the same id.
+func watchedFields(before, after Record) []FieldChange {
+	var fields []FieldChange
+	for _, field := range []struct {
+		name     string
+		from, to string
+	}{
+		{"name", before.Name, after.Name},
+		{"type", before.Type, after.Type},
+		{"content", before.Content, after.Content},
+	} {
+		if field.from != field.to {
+			fields = append(fields, FieldChange{Field: field.name, Old: field.from, New: field.to})
+		}
+	}
+	return append(fields, diffFields(before, after)...)
+}
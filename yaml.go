@@ -0,0 +1,118 @@
+package cloudflare
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* YAML output of rendered values */
+
+// yamlEncode writes a value made of orderedMap, []interface{} and scalars,
+// as produced by formatGeneric.
+func yamlEncode(w io.Writer, v interface{}) error {
+	var b strings.Builder
+	yamlWriteValue(&b, v, 0)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func yamlWriteValue(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch value := v.(type) {
+	case orderedMap:
+		if len(value) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		for _, field := range value {
+			b.WriteString(pad + yamlScalarString(field.Key) + ":")
+			switch child := field.Value.(type) {
+			case orderedMap:
+				if len(child) == 0 {
+					b.WriteString(" {}\n")
+					continue
+				}
+				b.WriteString("\n")
+				yamlWriteValue(b, child, indent+2)
+			case []interface{}:
+				if len(child) == 0 {
+					b.WriteString(" []\n")
+					continue
+				}
+				b.WriteString("\n")
+				yamlWriteValue(b, child, indent)
+			default:
+				b.WriteString(" " + yamlScalarString(child) + "\n")
+			}
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range value {
+			var nested strings.Builder
+			yamlWriteValue(&nested, item, indent+2)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	default:
+		b.WriteString(pad + yamlScalarString(value) + "\n")
+	}
+}
+
+func yamlScalarString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case float32:
+		return yamlFloat(float64(value))
+	case float64:
+		return yamlFloat(value)
+	case string:
+		if yamlNeedsQuotes(value) {
+			return strconv.Quote(value)
+		}
+		return value
+	}
+	return fmt.Sprint(v)
+}
+
+func yamlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// yamlNeedsQuotes reports whether s would not read back as the same
+// string when written plain.
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off", ".nan", ".inf", "-.inf", "+.inf":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsAny(s, "\n\r\t")
+}
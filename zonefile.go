@@ -0,0 +1,628 @@
+package cloudflare
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+/* Record input accepted by rec_new and rec_edit */
+
+type RecordInput struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Ttl     int    `json:"ttl"`
+	Prio    int    `json:"prio"`
+	Proxied bool   `json:"proxied"`
+}
+
+// Values returns the request arguments expected by NewDnsRecord and
+// EditDnsRecord. A zero Ttl means automatic (1).
+func (this RecordInput) Values() map[string]string {
+	ttl := this.Ttl
+	if ttl <= 0 {
+		ttl = 1
+	}
+
+	values := make(map[string]string)
+	values["type"] = this.Type
+	values["name"] = this.Name
+	values["content"] = this.Content
+	values["ttl"] = strconv.Itoa(ttl)
+
+	switch this.Type {
+	case "MX":
+		values["prio"] = strconv.Itoa(this.Prio)
+	case "SRV":
+		values["prio"] = strconv.Itoa(this.Prio)
+		labels := strings.SplitN(this.Name, ".", 3)
+		if len(labels) == 3 {
+			values["service"] = labels[0]
+			values["protocol"] = labels[1]
+			values["srvname"] = labels[2]
+		}
+		fields := strings.Fields(this.Content)
+		if len(fields) == 3 {
+			values["weight"] = fields[0]
+			values["port"] = fields[1]
+			values["target"] = fields[2]
+		}
+	case "A", "AAAA", "CNAME":
+		if this.Proxied {
+			values["service_mode"] = "1"
+		} else {
+			values["service_mode"] = "0"
+		}
+	}
+	return values
+}
+
+// Input converts a record returned by rec_load_all into a RecordInput.
+func (this Record) Input() RecordInput {
+	ttl, _ := strconv.Atoi(this.Ttl)
+	if ttl == 1 {
+		ttl = 0
+	}
+	prio, _ := strconv.Atoi(this.Prio)
+	return RecordInput{
+		Name:    this.Name,
+		Type:    this.Type,
+		Content: this.Content,
+		Ttl:     ttl,
+		Prio:    prio,
+		Proxied: this.ServiceMode == "1",
+	}
+}
+
+func (this RecordInput) String() string {
+	s := fmt.Sprintf("%s %d %s", this.Name, this.Ttl, this.Type)
+	if this.Type == "MX" || this.Type == "SRV" {
+		s += " " + strconv.Itoa(this.Prio)
+	}
+	s += " " + this.Content
+	if this.Proxied {
+		s += " (proxied)"
+	}
+	return s
+}
+
+/* Zone file import and export */
+
+const (
+	ZONE_PROXIED  string = "cf-proxied"
+	ZONE_AUTO_TTL string = "cf-auto-ttl"
+	ZONE_TTL      int    = 300
+)
+
+type ZoneFile struct {
+	Origin      string
+	Records     []RecordInput
+	Unsupported []UnsupportedRecord
+}
+
+type UnsupportedRecord struct {
+	Line   int
+	Name   string
+	Type   string
+	Data   string
+	Reason string
+}
+
+type zoneEntry struct {
+	line    int
+	blank   bool
+	fields  []string
+	comment string
+}
+
+// ParseZoneFile reads an RFC 1035 master file. Relative names are qualified
+// against origin until a $ORIGIN directive changes it.
+func ParseZoneFile(r io.Reader, origin string) (ZoneFile, error) {
+	zone := ZoneFile{Origin: strings.TrimSuffix(origin, ".")}
+
+	entries, err := readZoneEntries(r)
+	if err != nil {
+		return ZoneFile{}, err
+	}
+
+	current := zone.Origin
+	owner := ""
+	defaultTtl := 0
+	lastTtl := 0
+
+	for _, entry := range entries {
+		fields := entry.fields
+
+		if strings.HasPrefix(fields[0], "$") && !entry.blank {
+			switch strings.ToUpper(fields[0]) {
+			case "$ORIGIN":
+				if len(fields) < 2 {
+					return ZoneFile{}, zoneError(entry.line, "$ORIGIN without a name")
+				}
+				current = qualifyName(fields[1], current)
+			case "$TTL":
+				if len(fields) < 2 {
+					return ZoneFile{}, zoneError(entry.line, "$TTL without a value")
+				}
+				ttl, err := parseZoneTtl(fields[1])
+				if err != nil {
+					return ZoneFile{}, zoneError(entry.line, err.Error())
+				}
+				defaultTtl = ttl
+			default:
+				return ZoneFile{}, zoneError(entry.line, "unsupported directive "+fields[0])
+			}
+			continue
+		}
+
+		if !entry.blank {
+			owner = qualifyName(fields[0], current)
+			fields = fields[1:]
+		}
+		if owner == "" {
+			return ZoneFile{}, zoneError(entry.line, "record without an owner name")
+		}
+
+		ttl := -1
+		for len(fields) > 0 {
+			if isZoneClass(fields[0]) {
+				if strings.ToUpper(fields[0]) != "IN" {
+					return ZoneFile{}, zoneError(entry.line, "unsupported class "+fields[0])
+				}
+				fields = fields[1:]
+				continue
+			}
+			if t, err := parseZoneTtl(fields[0]); err == nil && ttl < 0 {
+				ttl = t
+				fields = fields[1:]
+				continue
+			}
+			break
+		}
+		if len(fields) == 0 {
+			return ZoneFile{}, zoneError(entry.line, "record without a type")
+		}
+		if ttl < 0 {
+			ttl = defaultTtl
+			if ttl == 0 {
+				ttl = lastTtl
+			}
+		} else {
+			lastTtl = ttl
+		}
+
+		rtype := strings.ToUpper(fields[0])
+		rdata := fields[1:]
+
+		record, reason, err := zoneRecord(owner, rtype, ttl, rdata, current, zone.Origin)
+		if err != nil {
+			return ZoneFile{}, zoneError(entry.line, err.Error())
+		}
+		for _, marker := range strings.Fields(entry.comment) {
+			switch marker {
+			case ZONE_PROXIED:
+				record.Proxied = true
+			case ZONE_AUTO_TTL:
+				record.Ttl = 0
+			}
+		}
+		if reason != "" {
+			zone.Unsupported = append(zone.Unsupported, UnsupportedRecord{
+				Line:   entry.line,
+				Name:   owner,
+				Type:   rtype,
+				Data:   strings.Join(rdata, " "),
+				Reason: reason,
+			})
+			continue
+		}
+		zone.Records = append(zone.Records, record)
+	}
+	return zone, nil
+}
+
+// zoneRecord converts a resource record. Relative names in rdata are
+// qualified against origin; apex is the zone being imported.
+func zoneRecord(owner, rtype string, ttl int, rdata []string, origin, apex string) (RecordInput, string, error) {
+	record := RecordInput{Name: owner, Type: rtype, Ttl: ttl}
+
+	switch rtype {
+	case "A", "AAAA":
+		if len(rdata) != 1 {
+			return record, "", errors.New(rtype + " record needs one address")
+		}
+		ip := net.ParseIP(rdata[0])
+		if ip == nil || (rtype == "A") != (ip.To4() != nil) {
+			return record, "", errors.New("invalid " + rtype + " address " + rdata[0])
+		}
+		record.Content = ip.String()
+	case "CNAME", "NS":
+		if len(rdata) != 1 {
+			return record, "", errors.New(rtype + " record needs one target")
+		}
+		if rtype == "NS" && owner == apex {
+			return record, "apex NS records are managed by Cloudflare", nil
+		}
+		record.Content = qualifyName(rdata[0], origin)
+	case "MX":
+		if len(rdata) != 2 {
+			return record, "", errors.New("MX record needs a preference and an exchange")
+		}
+		prio, err := strconv.Atoi(rdata[0])
+		if err != nil {
+			return record, "", errors.New("invalid MX preference " + rdata[0])
+		}
+		record.Prio = prio
+		record.Content = qualifyName(rdata[1], origin)
+	case "TXT", "SPF":
+		if len(rdata) == 0 {
+			return record, "", errors.New(rtype + " record needs at least one string")
+		}
+		record.Content = strings.Join(rdata, "")
+	case "SRV":
+		if len(rdata) != 4 {
+			return record, "", errors.New("SRV record needs priority, weight, port and target")
+		}
+		for _, n := range rdata[:3] {
+			if _, err := strconv.Atoi(n); err != nil {
+				return record, "", errors.New("invalid SRV field " + n)
+			}
+		}
+		record.Prio, _ = strconv.Atoi(rdata[0])
+		record.Content = rdata[1] + " " + rdata[2] + " " + qualifyName(rdata[3], origin)
+	case "SOA":
+		return record, "SOA is managed by Cloudflare", nil
+	default:
+		return record, "record type not supported by the API", nil
+	}
+	return record, "", nil
+}
+
+func readZoneEntries(r io.Reader) ([]zoneEntry, error) {
+	var entries []zoneEntry
+	var current *zoneEntry
+	depth := 0
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := scanner.Text()
+
+		if depth == 0 {
+			current = &zoneEntry{
+				line:  number,
+				blank: len(line) > 0 && (line[0] == ' ' || line[0] == '\t'),
+			}
+		}
+
+		fields, comment, opened, err := splitZoneLine(line)
+		if err != nil {
+			return nil, zoneError(number, err.Error())
+		}
+		depth += opened
+		if depth < 0 {
+			return nil, zoneError(number, "unbalanced parentheses")
+		}
+		current.fields = append(current.fields, fields...)
+		if comment != "" {
+			current.comment = comment
+		}
+
+		if depth == 0 && len(current.fields) > 0 {
+			entries = append(entries, *current)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if depth != 0 {
+		return nil, zoneError(number, "unterminated parentheses")
+	}
+	return entries, nil
+}
+
+// splitZoneLine breaks a line into fields, dropping parentheses and the
+// trailing comment. It returns the change in parenthesis depth.
+func splitZoneLine(line string) ([]string, string, int, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	quoted := false
+	depth := 0
+
+	flush := func() {
+		if inField {
+			fields = append(fields, field.String())
+			field.Reset()
+			inField = false
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+			inField = true
+		case c == '"':
+			if quoted {
+				quoted = false
+				flush()
+			} else {
+				flush()
+				quoted = true
+				inField = true
+			}
+		case quoted:
+			field.WriteByte(c)
+		case c == ';':
+			flush()
+			return fields, strings.TrimSpace(line[i+1:]), depth, nil
+		case c == '(':
+			flush()
+			depth++
+		case c == ')':
+			flush()
+			depth--
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, "", 0, errors.New("unterminated quoted string")
+	}
+	flush()
+	return fields, "", depth, nil
+}
+
+func qualifyName(name, origin string) string {
+	if name == "@" {
+		return origin
+	}
+	if strings.HasSuffix(name, ".") {
+		return strings.TrimSuffix(name, ".")
+	}
+	if origin == "" {
+		return name
+	}
+	return name + "." + origin
+}
+
+func isZoneClass(s string) bool {
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "HS", "CS":
+		return true
+	}
+	return false
+}
+
+// parseZoneTtl accepts plain seconds as well as BIND units (1h30m, 2d, 1w).
+func parseZoneTtl(s string) (int, error) {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0, errors.New("invalid ttl " + s)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+
+	total := 0
+	n := 0
+	digits := false
+	for _, c := range strings.ToLower(s) {
+		if c >= '0' && c <= '9' {
+			n = n*10 + int(c-'0')
+			digits = true
+			continue
+		}
+		if !digits {
+			return 0, errors.New("invalid ttl " + s)
+		}
+		switch c {
+		case 's':
+		case 'm':
+			n *= 60
+		case 'h':
+			n *= 3600
+		case 'd':
+			n *= 86400
+		case 'w':
+			n *= 604800
+		default:
+			return 0, errors.New("invalid ttl " + s)
+		}
+		total += n
+		n = 0
+		digits = false
+	}
+	if digits {
+		return 0, errors.New("invalid ttl " + s)
+	}
+	return total, nil
+}
+
+func zoneError(line int, message string) error {
+	return fmt.Errorf("zone file line %d: %s", line, message)
+}
+
+// Preview writes the records that ImportZoneFile would create, followed by
+// the entries it will skip.
+func (this ZoneFile) Preview(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, record := range this.Records {
+		prio := ""
+		if record.Type == "MX" || record.Type == "SRV" {
+			prio = strconv.Itoa(record.Prio)
+		}
+		fmt.Fprintf(tw, "+\t%s\t%d\t%s\t%s\t%s\n", record.Name, record.Ttl, record.Type, prio, record.Content)
+	}
+	for _, skipped := range this.Unsupported {
+		fmt.Fprintf(tw, "!\t%s\t\t%s\t\t%s\t(line %d: %s)\n", skipped.Name, skipped.Type, skipped.Data, skipped.Line, skipped.Reason)
+	}
+	return tw.Flush()
+}
+
+// ImportZoneFile creates every supported record of zone in domain. It stops
+// at the first failure and returns the records created so far.
+func (this *Cloudflare) ImportZoneFile(domain string, zone ZoneFile) ([]Record, error) {
+	var created []Record
+	for _, record := range zone.Records {
+		response, err := this.NewDnsRecord(domain, record.Values())
+		if err != nil {
+			return created, fmt.Errorf("%s: %s", record, err)
+		}
+		created = append(created, response.Response.Rec)
+	}
+	return created, nil
+}
+
+// WriteZoneFile writes records as a BIND master file for domain. Records are
+// sorted by name, type and content so that repeated exports are identical.
+// Proxied records and automatic TTLs are kept as trailing comments which
+// ParseZoneFile understands.
+func WriteZoneFile(w io.Writer, domain string, records []Record) error {
+	domain = strings.TrimSuffix(domain, ".")
+
+	inputs := make([]RecordInput, 0, len(records))
+	for _, record := range records {
+		inputs = append(inputs, record.Input())
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		a, b := inputs[i], inputs[j]
+		if a.Name != b.Name {
+			return zoneNameLess(a.Name, b.Name)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Prio != b.Prio {
+			return a.Prio < b.Prio
+		}
+		return a.Content < b.Content
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "; %s\n", domain)
+	fmt.Fprintf(bw, "$ORIGIN %s.\n", domain)
+	fmt.Fprintf(bw, "$TTL %d\n\n", ZONE_TTL)
+
+	tw := tabwriter.NewWriter(bw, 0, 8, 1, '\t', 0)
+	for _, record := range inputs {
+		ttl := ""
+		if record.Ttl > 0 {
+			ttl = strconv.Itoa(record.Ttl)
+		}
+
+		var comments []string
+		if record.Proxied {
+			comments = append(comments, ZONE_PROXIED)
+		}
+		if record.Ttl == 0 {
+			comments = append(comments, ZONE_AUTO_TTL)
+		}
+
+		line := fmt.Sprintf("%s\t%s\tIN\t%s\t%s", relativeName(record.Name, domain), ttl, record.Type, zoneRdata(record))
+		if len(comments) > 0 {
+			line += "\t; " + strings.Join(comments, " ")
+		}
+		fmt.Fprintln(tw, line)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func zoneRdata(record RecordInput) string {
+	switch record.Type {
+	case "CNAME", "NS":
+		return absoluteName(record.Content)
+	case "MX":
+		return strconv.Itoa(record.Prio) + " " + absoluteName(record.Content)
+	case "SRV":
+		fields := strings.Fields(record.Content)
+		if len(fields) == 4 {
+			fields = fields[1:]
+		}
+		if len(fields) == 3 {
+			fields[2] = absoluteName(fields[2])
+		}
+		return strconv.Itoa(record.Prio) + " " + strings.Join(fields, " ")
+	case "TXT", "SPF":
+		return quoteZoneText(record.Content)
+	}
+	return record.Content
+}
+
+// quoteZoneText quotes s, splitting it into the 255 byte character strings
+// allowed by RFC 1035.
+func quoteZoneText(s string) string {
+	var parts []string
+	for {
+		chunk := s
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		chunk = strings.Replace(chunk, "\\", "\\\\", -1)
+		chunk = strings.Replace(chunk, "\"", "\\\"", -1)
+		parts = append(parts, "\""+chunk+"\"")
+		if len(s) <= 255 {
+			break
+		}
+		s = s[255:]
+	}
+	return strings.Join(parts, " ")
+}
+
+func relativeName(name, origin string) string {
+	name = strings.TrimSuffix(name, ".")
+	if name == origin {
+		return "@"
+	}
+	if strings.HasSuffix(name, "."+origin) {
+		return strings.TrimSuffix(name, "."+origin)
+	}
+	return name + "."
+}
+
+func absoluteName(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// zoneNameLess orders names label by label from the root, which keeps the
+// apex first and groups subdomains together.
+func zoneNameLess(a, b string) bool {
+	la := strings.Split(a, ".")
+	lb := strings.Split(b, ".")
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if la[i] != lb[j] {
+			return la[i] < lb[j]
+		}
+	}
+	return len(la) < len(lb)
+}
+
+// ExportZoneFile fetches the records of domain and writes them with
+// WriteZoneFile.
+func (this *Cloudflare) ExportZoneFile(w io.Writer, domain string) error {
+	records, err := this.GetDnsRecords(domain)
+	if err != nil {
+		return err
+	}
+	return WriteZoneFile(w, domain, records.Response.Objs)
+}
@@ -0,0 +1,172 @@
+package cloudflare
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		records     []RecordInput
+		unsupported []string
+	}{
+		{
+			name: "directives",
+			input: `$TTL 1h
+@	IN	A	192.0.2.1
+$ORIGIN sub.example.com.
+www		A	192.0.2.2
+@		NS	ns1.other.net.
+`,
+			records: []RecordInput{
+				{Name: "example.com", Type: "A", Content: "192.0.2.1", Ttl: 3600},
+				{Name: "www.sub.example.com", Type: "A", Content: "192.0.2.2", Ttl: 3600},
+				{Name: "sub.example.com", Type: "NS", Content: "ns1.other.net", Ttl: 3600},
+			},
+		},
+		{
+			name: "apex records",
+			input: `@	3600	IN	SOA	ns1.example.com. admin.example.com. 1 7200 3600 1209600 300
+@	3600	IN	NS	ns1.example.com.
+@	3600	IN	HINFO	"cpu" "os"
+`,
+			unsupported: []string{"SOA", "NS", "HINFO"},
+		},
+		{
+			name: "multi-line record and blank owner",
+			input: `mail	600	IN	MX	( 10
+				mx1 )	; primary
+	600	IN	MX	20 mx2.example.net.
+_sip._tcp	300	IN	SRV	(
+	10 60 5060
+	sip )
+`,
+			records: []RecordInput{
+				{Name: "mail.example.com", Type: "MX", Content: "mx1.example.com", Ttl: 600, Prio: 10},
+				{Name: "mail.example.com", Type: "MX", Content: "mx2.example.net", Ttl: 600, Prio: 20},
+				{Name: "_sip._tcp.example.com", Type: "SRV", Content: "60 5060 sip.example.com", Ttl: 300, Prio: 10},
+			},
+		},
+		{
+			name: "quoting",
+			input: `txt	300	TXT	"v=spf1 include:example.net ~all"
+long	300	TXT	"first; part " "second \"quoted\" part"
+esc	300	TXT	"back\\slash"
+`,
+			records: []RecordInput{
+				{Name: "txt.example.com", Type: "TXT", Content: "v=spf1 include:example.net ~all", Ttl: 300},
+				{Name: "long.example.com", Type: "TXT", Content: `first; part second "quoted" part`, Ttl: 300},
+				{Name: "esc.example.com", Type: "TXT", Content: `back\slash`, Ttl: 300},
+			},
+		},
+		{
+			name: "ttl units and inheritance",
+			input: `a	1h30m	A	192.0.2.1
+	IN	A	192.0.2.2
+b	2d	IN	A	192.0.2.3
+c	1W	A	192.0.2.4
+d	90s	AAAA	2001:db8::1
+`,
+			records: []RecordInput{
+				{Name: "a.example.com", Type: "A", Content: "192.0.2.1", Ttl: 5400},
+				{Name: "a.example.com", Type: "A", Content: "192.0.2.2", Ttl: 5400},
+				{Name: "b.example.com", Type: "A", Content: "192.0.2.3", Ttl: 172800},
+				{Name: "c.example.com", Type: "A", Content: "192.0.2.4", Ttl: 604800},
+				{Name: "d.example.com", Type: "AAAA", Content: "2001:db8::1", Ttl: 90},
+			},
+		},
+		{
+			name: "cloudflare markers",
+			input: `www	300	IN	CNAME	example.com.	; cf-proxied
+api		IN	A	192.0.2.1	; cf-proxied cf-auto-ttl
+`,
+			records: []RecordInput{
+				{Name: "www.example.com", Type: "CNAME", Content: "example.com", Ttl: 300, Proxied: true},
+				{Name: "api.example.com", Type: "A", Content: "192.0.2.1", Ttl: 0, Proxied: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		zone, err := ParseZoneFile(strings.NewReader(test.input), "example.com.")
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(zone.Records, test.records) {
+			t.Errorf("%s: records\n got %+v\nwant %+v", test.name, zone.Records, test.records)
+		}
+		var unsupported []string
+		for _, record := range zone.Unsupported {
+			unsupported = append(unsupported, record.Type)
+		}
+		if !reflect.DeepEqual(unsupported, test.unsupported) {
+			t.Errorf("%s: unsupported %v, want %v", test.name, unsupported, test.unsupported)
+		}
+	}
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"$INCLUDE other.zone\n", "line 1: unsupported directive $INCLUDE"},
+		{"$TTL\n", "line 1: $TTL without a value"},
+		{"a 300 IN A ( 192.0.2.1\n", "line 1: unterminated parentheses"},
+		{"a 300 IN A 192.0.2.1 )\n", "line 1: unbalanced parentheses"},
+		{"a 300 IN TXT \"open\n", "line 1: unterminated quoted string"},
+		{"\n\ta 300 CH A 192.0.2.1\n", "line 2: record without an owner name"},
+		{"a 300 CH A 192.0.2.1\n", "line 1: unsupported class CH"},
+		{"a 300 IN A 2001:db8::1\n", "line 1: invalid A address 2001:db8::1"},
+		{"a 300 IN MX mx1\n", "line 1: MX record needs a preference and an exchange"},
+		{"a 300 IN\n", "line 1: record without a type"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseZoneFile(strings.NewReader(test.input), "example.com")
+		if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+			t.Errorf("%q: error %v, want %q", test.input, err, test.err)
+		}
+	}
+}
+
+func TestZoneFileRoundTrip(t *testing.T) {
+	records := []Record{
+		{Name: "example.com", Type: "A", Content: "192.0.2.1", Ttl: "1", ServiceMode: "1"},
+		{Name: "www.example.com", Type: "CNAME", Content: "example.com", Ttl: "300", ServiceMode: "0"},
+		{Name: "example.com", Type: "MX", Content: "mx1.example.net", Ttl: "3600", Prio: "10"},
+		{Name: "example.com", Type: "TXT", Content: `v=spf1 include:"quoted" ~all; \back`, Ttl: "1"},
+		{Name: "long.example.com", Type: "TXT", Content: strings.Repeat("0123456789", 30), Ttl: "120"},
+		{Name: "_sip._tcp.example.com", Type: "SRV", Content: "60 5060 sip.example.com", Ttl: "300", Prio: "10"},
+		{Name: "v6.example.com", Type: "AAAA", Content: "2001:db8::1", Ttl: "300", ServiceMode: "0"},
+	}
+
+	var b strings.Builder
+	if err := WriteZoneFile(&b, "example.com", records); err != nil {
+		t.Fatal(err)
+	}
+	zone, err := ParseZoneFile(strings.NewReader(b.String()), "example.com")
+	if err != nil {
+		t.Fatalf("%s\n%s", err, b.String())
+	}
+	if len(zone.Unsupported) > 0 {
+		t.Errorf("unsupported records: %+v", zone.Unsupported)
+	}
+
+	want := make(map[string]RecordInput)
+	for _, record := range records {
+		input := record.Input()
+		want[input.Name+" "+input.Type] = input
+	}
+	got := make(map[string]RecordInput)
+	for _, input := range zone.Records {
+		got[input.Name+" "+input.Type] = input
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip\n got %+v\nwant %+v\nzone file:\n%s", got, want, b.String())
+	}
+}